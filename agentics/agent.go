@@ -3,9 +3,10 @@ package agentics
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"sync"
 
 	"github.com/valyala/fasttemplate"
 )
@@ -22,31 +23,41 @@ type Agent struct {
 	Branchs          []string
 	Conditional      func(bag *Bag[any]) string
 	Tools            []ToolInterface
+	InputGuardrails  []string
 	OutputGuardrails []string
-	OutputType       string
+	OutputType       *OutputSchema
+	OutputKey        string
+	MaxIterations    int
+	ParallelTools    int
+	inputGuardrails  []namedGuardrail
+	outputGuardrails []namedGuardrail
+	diagnostics      []Diagnostic
 	hooks            []struct {
 		kind Kind
 		fn   Func
 	}
 }
 
-type NextAgent struct {
-	Next string `json:"next"`
-}
+var ErrMaxIterationsExceeded = errors.New("max iterations exceeded")
+
+const defaultMaxIterations = 10
+
 type AgentOption func(*Agent)
 
 type AgentResponse struct {
 	Content   string
+	Output    interface{}
 	Error     error
 	NextAgent string
 }
 
 func NewAgent(name string, instructions string, options ...AgentOption) *Agent {
 	agent := &Agent{
-		Name:         name,
-		Instructions: instructions,
-		Client:       NewModelClient(OpenAI),
-		Model:        "",
+		Name:          name,
+		Instructions:  instructions,
+		Client:        NewModelClient(OpenAI),
+		Model:         "",
+		MaxIterations: defaultMaxIterations,
 	}
 
 	for _, option := range options {
@@ -67,10 +78,6 @@ func WithClient(client ModelClient) AgentOption {
 
 func WithBranchs(branchs []string) AgentOption {
 	return func(a *Agent) {
-		for _, branch := range branchs {
-			a.Instructions = a.Instructions +
-				"Response with {\"next\": \"" + branch + "\"} and appropiate agent."
-		}
 		a.Branchs = branchs
 	}
 }
@@ -81,18 +88,32 @@ func WithTools(tools []ToolInterface) AgentOption {
 	}
 }
 
+func WithInputGuardrails(guardrails []string) AgentOption {
+	return func(a *Agent) {
+		a.InputGuardrails = guardrails
+		a.inputGuardrails = a.resolveGuardrails(guardrails)
+	}
+}
+
 func WithOutputGuardrails(guardrails []string) AgentOption {
 	return func(a *Agent) {
 		a.OutputGuardrails = guardrails
+		a.outputGuardrails = a.resolveGuardrails(guardrails)
 	}
 }
 
-func WithOutputType(outputType string) AgentOption {
+func WithOutputType(outputType *OutputSchema) AgentOption {
 	return func(a *Agent) {
 		a.OutputType = outputType
 	}
 }
 
+func WithOutputKey(key string) AgentOption {
+	return func(a *Agent) {
+		a.OutputKey = key
+	}
+}
+
 func WithModel(model string) AgentOption {
 	return func(a *Agent) {
 		a.Model = model
@@ -102,6 +123,18 @@ func WithModel(model string) AgentOption {
 	}
 }
 
+func WithMaxIterations(maxIterations int) AgentOption {
+	return func(a *Agent) {
+		a.MaxIterations = maxIterations
+	}
+}
+
+func WithParallelTools(n int) AgentOption {
+	return func(a *Agent) {
+		a.ParallelTools = n
+	}
+}
+
 func WithConditional(conditional func(bag *Bag[any]) string) AgentOption {
 	return func(a *Agent) {
 		a.Conditional = conditional
@@ -116,12 +149,40 @@ func WithHooks(kind Kind, name string) AgentOption {
 				fn   Func
 			}{kind, fn})
 		} else {
-			panic("hook no registrado: " + name)
+			withDiagnostic(Diagnostic{
+				Severity: SeverityError,
+				Code:     DiagUnregisteredHook,
+				Message:  fmt.Sprintf("hook %q is not registered", name),
+			})(a)
 		}
 	}
 }
 
 func (a *Agent) Run(ctx context.Context, bag *Bag[any], mem Memory) AgentResponse {
+	return a.run(ctx, bag, mem, nil)
+}
+
+// RunStream emite los eventos de la corrida. Si el agente tiene guardrails de
+// salida la respuesta llega en un solo EventToken, despues de pasarlos.
+func (a *Agent) RunStream(ctx context.Context, bag *Bag[any], mem Memory, handler EventHandler) AgentResponse {
+	emit := syncHandler(handler)
+
+	emit(Event{Type: EventAgentStarted, Agent: a.Name})
+	response := a.run(ctx, bag, mem, emit)
+	emit(Event{Type: EventAgentFinished, Agent: a.Name, Response: &response})
+
+	return response
+}
+
+func (a *Agent) run(ctx context.Context, bag *Bag[any], mem Memory, emit EventHandler) AgentResponse {
+	if len(a.diagnostics) > 0 {
+		return AgentResponse{
+			Content:   "",
+			Error:     &ValidationError{Diagnostics: a.diagnostics},
+			NextAgent: "",
+		}
+	}
+
 	fmt.Printf("Running agent: %s\n", a.Name)
 	nextAgent := ""
 
@@ -131,6 +192,10 @@ func (a *Agent) Run(ctx context.Context, bag *Bag[any], mem Memory) AgentRespons
 		}
 	}
 
+	// Si la corrida se reanuda despues de un interrupt en este agente, los
+	// pre hooks ya corrieron y la vuelta sigue desde lo guardado
+	turn := takeTurn(ctx, a.Name)
+
 	var c *Context
 	if len(a.hooks) > 0 {
 		c = &Context{
@@ -141,9 +206,25 @@ func (a *Agent) Run(ctx context.Context, bag *Bag[any], mem Memory) AgentRespons
 		}
 	}
 
+	// Un post hook pidio intervencion humana: la respuesta ya esta en la
+	// memoria y el Bag, solo faltan los post hooks
+	if turn != nil && turn.Finished {
+		response := AgentResponse{Content: turn.Content, NextAgent: turn.NextAgent}
+		if a.OutputKey != "" {
+			response.Output = bag.Get(a.OutputKey)
+		}
+		return a.postHooks(ctx, c, response)
+	}
+
 	for _, h := range a.hooks {
-		if h.kind == PreHook {
-			h.fn(ctx, c)
+		if h.kind == PreHook && turn == nil {
+			if err := h.fn(ctx, c); errors.Is(err, ErrInterrupted) {
+				return AgentResponse{
+					Content:   "",
+					Error:     err,
+					NextAgent: "",
+				}
+			}
 		}
 	}
 
@@ -165,96 +246,372 @@ func (a *Agent) Run(ctx context.Context, bag *Bag[any], mem Memory) AgentRespons
 
 	prompt := tpl.ExecuteString(stringValues)
 
-	response, err := a.Client.provider.Execute(
-		ctx,
-		prompt,
-		mem.All(),
-		a.Tools,
-	)
-	if err != nil {
-		fmt.Println("Error executing agent:", err)
-		return AgentResponse{
-			Content:   "",
-			Error:     err,
-			NextAgent: "",
+	maxIterations := a.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultMaxIterations
+	}
+
+	// Cada branch se ofrece al modelo como una tool transfer_to_<agente>
+	tools := append(append([]ToolInterface{}, a.Tools...), handoffTools(a.Branchs)...)
+
+	// Lo que la memoria recorto se resume antes de leerla, no al agregarlo. Si
+	// falla se sigue con los mensajes sin resumir
+	if summarizing, ok := mem.(SummarizingMemory); ok {
+		if err := summarizing.Summarize(ctx); err != nil {
+			fmt.Println("Error summarizing memory:", err)
 		}
 	}
-	if response.IsToolCall {
-		for _, tool := range a.Tools {
-			for _, toolCall := range response.ToolCalls {
-				if tool.GetName() == toolCall.Name {
-					params := make(map[string]interface{})
-					if err := json.Unmarshal([]byte(toolCall.Arguments), &params); err != nil {
-						fmt.Println("Error unmarshalling tool call arguments:", err)
-						return AgentResponse{
-							Content:   "",
-							Error:     err,
-							NextAgent: "",
-						}
-					}
 
-					// TODO: Refactor?
-					for k, v := range params {
-						if floatVal, ok := v.(float64); ok && floatVal == float64(int(floatVal)) {
-							params[k] = int(floatVal)
-						}
-					}
+	messages := mem.All()
+	if len(a.inputGuardrails) > 0 {
+		var tripped *AgentResponse
+		if prompt, tripped = a.checkInput(ctx, bag, mem, prompt, messages); tripped != nil {
+			return *tripped
+		}
+	}
 
-					output := tool.Run(ctx, bag, &ToolParams{Params: params})
+	// El pase que recibio este agente va al final de la conversacion, sin
+	// guardarse en la memoria
+	received := handoffFor(bag, a.Name)
+	if received != nil {
+		messages = append(messages, Message{Role: "system", Content: received.String()})
+	}
 
-					toolResultMessage := fmt.Sprintf("I used the %s tool with the arguments %s and got this result: %s. Please provide a final response based on this information.",
-						toolCall.Name, toolCall.Arguments, output.Output)
+	// Lo que sigue a start es la vuelta en curso, lo que se guarda si una
+	// tool pide intervencion humana
+	start := len(messages)
+	if turn != nil {
+		messages = append(messages, turn.Messages...)
+		if len(turn.Pending) > 0 {
+			results, pending, err := a.runTools(ctx, bag, turn.Pending, emit)
+			messages = append(messages, results...)
+			if err != nil {
+				return a.interrupted(err, messages[start:], pending)
+			}
+		}
+	}
 
-					followUpResponse, err := a.Client.provider.ExecuteWithFollowUp(
-						ctx,
-						prompt,
-						mem.All(),
-						a.Tools,
-						toolResultMessage,
-					)
-					if err != nil {
-						fmt.Println("Error executing tool follow-up:", err)
-						return AgentResponse{
-							Content:   "",
-							Error:     err,
-							NextAgent: "",
-						}
+	// Con guardrails de salida los tokens se retienen hasta que el contenido
+	// los pasa, para no mandar algo que despues se bloquea o se reescribe.
+	streamTokens := emit != nil && len(a.outputGuardrails) == 0
+
+	var response *ModelResponse
+	var content string
+	var output interface{}
+	var handoff *Handoff
+	for iteration := 0; ; iteration++ {
+		if iteration >= maxIterations {
+			err := fmt.Errorf("%w: agent %s", ErrMaxIterationsExceeded, a.Name)
+			fmt.Println("Error executing agent:", err)
+			return AgentResponse{
+				Content:   "",
+				Error:     err,
+				NextAgent: "",
+			}
+		}
+
+		var err error
+		if emit != nil {
+			response, err = a.Client.provider.ExecuteStream(
+				ctx,
+				prompt,
+				messages,
+				tools,
+				a.OutputType,
+				func(delta string) {
+					if streamTokens {
+						emit(Event{Type: EventToken, Agent: a.Name, Delta: delta})
 					}
+				},
+			)
+		} else {
+			response, err = a.Client.provider.Execute(
+				ctx,
+				prompt,
+				messages,
+				tools,
+				a.OutputType,
+			)
+		}
+		if err != nil {
+			fmt.Println("Error executing agent:", err)
+			return AgentResponse{
+				Content:   "",
+				Error:     err,
+				NextAgent: "",
+			}
+		}
+
+		// Un handoff junto con otras tools no se hace en esta vuelta, ver
+		// rejectHandoffs
+		handoff = a.findHandoff(response.ToolCalls)
+		if handoff != nil && len(response.ToolCalls) > 1 {
+			handoff = nil
+		}
 
+		if handoff != nil || len(response.ToolCalls) == 0 {
+			var tripped *GuardrailError
+			var result *GuardrailResult
+			content = response.GetContent()
+			// Un handoff sin texto no tiene nada que revisar
+			if handoff == nil || content != "" {
+				content, tripped, result = checkGuardrails(ctx, bag, a.outputGuardrails, content)
+			}
+			if tripped != nil {
+				fmt.Println("Output guardrail tripped:", tripped)
+				switch result.Action {
+				case GuardrailRetry:
+					messages = append(messages,
+						Message{Role: "assistant", Content: response.GetContent()},
+						Message{Role: "user", Content: fmt.Sprintf("Your response was rejected: %s. Please try again.", result.Reason)},
+					)
+					continue
+				case GuardrailRoute:
 					return AgentResponse{
-						Content:   followUpResponse.GetContent(),
+						Content:   "",
 						Error:     nil,
+						NextAgent: result.Route,
+					}
+				default:
+					return AgentResponse{
+						Content:   "",
+						Error:     tripped,
 						NextAgent: "",
 					}
 				}
 			}
+
+			if handoff != nil || a.OutputType == nil {
+				break
+			}
+
+			decoded, err := a.OutputType.Decode(content)
+			if err == nil {
+				output = decoded
+				break
+			}
+
+			fmt.Println("Error validating output:", err)
+			messages = append(messages,
+				Message{Role: "assistant", Content: content},
+				Message{Role: "user", Content: fmt.Sprintf("Your response does not match the expected schema: %v. Respond again with valid JSON.", err)},
+			)
+			continue
+		}
+
+		rejected, others := a.rejectHandoffs(response.ToolCalls)
+		messages = append(messages, Message{
+			Role:      "assistant",
+			Content:   response.GetContent(),
+			ToolCalls: response.ToolCalls,
+		})
+		messages = append(messages, rejected...)
+		results, pending, err := a.runTools(ctx, bag, others, emit)
+		messages = append(messages, results...)
+		if err != nil {
+			return a.interrupted(err, messages[start:], pending)
 		}
 	}
 
-	ressult := response.GetContent()
-	if strings.Contains(ressult, "next") {
-		var nextAgentStruct NextAgent
-		if err := json.Unmarshal([]byte(ressult), &nextAgentStruct); err != nil {
-			fmt.Println("Error unmarshalling next agent:", err)
-			return AgentResponse{
-				Content:   "",
-				Error:     err,
-				NextAgent: "",
+	if emit != nil && !streamTokens && content != "" {
+		emit(Event{Type: EventToken, Agent: a.Name, Delta: content})
+	}
+
+	if handoff == nil || content != "" {
+		mem.Add("assistant", content)
+	}
+	// El pase recibido ya se uso: no se repite en la proxima visita ni en la
+	// proxima corrida con el mismo Bag
+	if received != nil {
+		bag.Delete(HandoffKey)
+	}
+	if handoff != nil {
+		fmt.Printf("Handoff from %s to %s\n", handoff.From, handoff.To)
+		nextAgent = handoff.To
+		bag.Set(HandoffKey, *handoff)
+	}
+
+	if output != nil && a.OutputKey != "" {
+		bag.Set(a.OutputKey, output)
+	}
+
+	return a.postHooks(ctx, c, AgentResponse{
+		Content:   content,
+		Output:    output,
+		NextAgent: nextAgent,
+	})
+}
+
+// postHooks corre los post hooks sobre una respuesta ya guardada. Si uno pide
+// intervencion humana, al reanudar el agente vuelve directo a los post hooks.
+func (a *Agent) postHooks(ctx context.Context, c *Context, response AgentResponse) AgentResponse {
+	for _, h := range a.hooks {
+		if h.kind != PostHook {
+			continue
+		}
+		var interrupt *InterruptError
+		if err := h.fn(ctx, c); errors.As(err, &interrupt) {
+			interrupt.Interrupt.Turn = &Turn{
+				Agent:     a.Name,
+				Finished:  true,
+				Content:   response.Content,
+				NextAgent: response.NextAgent,
 			}
+			return AgentResponse{Error: err}
 		}
-		nextAgent = nextAgentStruct.Next
 	}
 
-	mem.Add("assistant", response.GetContent())
+	return response
+}
 
-	for _, h := range a.hooks {
-		if h.kind == PostHook {
-			h.fn(ctx, c)
+// checkInput evalua los guardrails de entrada sobre el ultimo mensaje del
+// usuario y sobre el prompt ya renderizado, antes de llamar al modelo.
+func (a *Agent) checkInput(ctx context.Context, bag *Bag[any], mem Memory, prompt string, messages []Message) (string, *AgentResponse) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "user" {
+			continue
+		}
+
+		content, tripped, result := checkGuardrails(ctx, bag, a.inputGuardrails, messages[i].Content)
+		if tripped != nil {
+			return prompt, a.inputTripped(mem, tripped, result)
+		}
+		messages[i].Content = content
+		break
+	}
+
+	prompt, tripped, result := checkGuardrails(ctx, bag, a.inputGuardrails, prompt)
+	if tripped != nil {
+		return prompt, a.inputTripped(mem, tripped, result)
+	}
+
+	return prompt, nil
+}
+
+func (a *Agent) inputTripped(mem Memory, tripped *GuardrailError, result *GuardrailResult) *AgentResponse {
+	fmt.Println("Input guardrail tripped:", tripped)
+
+	switch result.Action {
+	case GuardrailRespond:
+		mem.Add("assistant", result.Content)
+		return &AgentResponse{
+			Content:   result.Content,
+			Error:     nil,
+			NextAgent: Exitpoint,
+		}
+	case GuardrailRoute:
+		return &AgentResponse{
+			Content:   "",
+			Error:     nil,
+			NextAgent: result.Route,
+		}
+	}
+
+	return &AgentResponse{
+		Content:   "",
+		Error:     tripped,
+		NextAgent: "",
+	}
+}
+
+// interrupted guarda en el interrupt la vuelta en curso, para que al reanudar
+// no se repitan las tools que ya corrieron.
+func (a *Agent) interrupted(err error, messages []Message, pending []ToolCall) AgentResponse {
+	var interrupt *InterruptError
+	if errors.As(err, &interrupt) {
+		interrupt.Interrupt.Turn = &Turn{
+			Agent:    a.Name,
+			Messages: copyMessages(messages),
+			Pending:  pending,
 		}
 	}
 
 	return AgentResponse{
-		Content:   response.GetContent(),
-		NextAgent: nextAgent,
+		Content:   "",
+		Error:     err,
+		NextAgent: "",
 	}
 }
+
+// runTools devuelve error solo si una tool pidio intervencion humana; el resto
+// de los errores vuelven al modelo como mensajes de la tool. En ese caso
+// devuelve los resultados de las tools que terminaron y las que quedaron
+// pendientes.
+func (a *Agent) runTools(ctx context.Context, bag *Bag[any], toolCalls []ToolCall, emit EventHandler) ([]Message, []ToolCall, error) {
+	result := make([]Message, len(toolCalls))
+	errs := make([]error, len(toolCalls))
+
+	workers := a.ParallelTools
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for i, toolCall := range toolCalls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, toolCall ToolCall) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if emit != nil {
+				emit(Event{Type: EventToolCallStarted, Agent: a.Name, ToolCall: &toolCall})
+			}
+			output := a.runTool(ctx, bag, toolCall)
+			if emit != nil {
+				emit(Event{Type: EventToolCallFinished, Agent: a.Name, ToolCall: &toolCall, ToolResponse: output})
+			}
+			result[i] = Message{
+				Role:       "tool",
+				Content:    output.Output,
+				ToolCallID: toolCall.ToolCallID,
+				IsError:    output.IsError,
+			}
+			errs[i] = output.Error
+		}(i, toolCall)
+	}
+	wg.Wait()
+
+	var interrupt error
+	completed := []Message{}
+	pending := []ToolCall{}
+	for i, err := range errs {
+		if errors.Is(err, ErrInterrupted) {
+			if interrupt == nil {
+				interrupt = err
+			}
+			pending = append(pending, toolCalls[i])
+			continue
+		}
+		completed = append(completed, result[i])
+	}
+	if interrupt != nil {
+		return completed, pending, interrupt
+	}
+
+	return result, nil, nil
+}
+
+func (a *Agent) runTool(ctx context.Context, bag *Bag[any], toolCall ToolCall) *ToolResponse {
+	for _, tool := range a.Tools {
+		if tool.GetName() != toolCall.Name {
+			continue
+		}
+
+		params := make(map[string]interface{})
+		if toolCall.Arguments != "" {
+			if err := json.Unmarshal([]byte(toolCall.Arguments), &params); err != nil {
+				fmt.Println("Error unmarshalling tool call arguments:", err)
+				return toolError(fmt.Errorf("invalid arguments for tool %s: %w", toolCall.Name, err))
+			}
+		}
+
+		return tool.Run(ctx, bag, &ToolParams{
+			Params:    params,
+			Arguments: toolCall.Arguments,
+		})
+	}
+
+	return toolError(fmt.Errorf("tool %s not found", toolCall.Name))
+}
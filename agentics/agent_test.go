@@ -0,0 +1,184 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProvider devuelve respuestas armadas a mano, en orden, y guarda los
+// mensajes que recibio en cada llamada.
+type fakeProvider struct {
+	mu        sync.Mutex
+	model     string
+	responses []*ModelResponse
+	calls     [][]Message
+	prompts   []string
+	respond   func(ctx context.Context, messages []Message) (*ModelResponse, error)
+}
+
+func withFake(p *fakeProvider) AgentOption {
+	return WithClient(ModelClient{provider: p})
+}
+
+func text(content string) *ModelResponse {
+	return &ModelResponse{Content: content}
+}
+
+func toolCalls(calls ...ToolCall) *ModelResponse {
+	return &ModelResponse{IsToolCall: true, ToolCalls: calls}
+}
+
+func (p *fakeProvider) Execute(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, output *OutputSchema) (*ModelResponse, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, copyMessages(messages))
+	p.prompts = append(p.prompts, prompt)
+	respond := p.respond
+	var response *ModelResponse
+	if respond == nil {
+		if len(p.responses) == 0 {
+			p.mu.Unlock()
+			return nil, errors.New("fake provider: no more responses")
+		}
+		response = p.responses[0]
+		p.responses = p.responses[1:]
+	}
+	p.mu.Unlock()
+
+	if respond != nil {
+		return respond(ctx, messages)
+	}
+	return response, nil
+}
+
+// ExecuteStream manda el contenido palabra por palabra.
+func (p *fakeProvider) ExecuteStream(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, output *OutputSchema, onDelta func(string)) (*ModelResponse, error) {
+	response, err := p.Execute(ctx, prompt, messages, tools, output)
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range strings.SplitAfter(response.Content, " ") {
+		if word != "" {
+			onDelta(word)
+		}
+	}
+	return response, nil
+}
+
+func (p *fakeProvider) GetModel() string {
+	return p.model
+}
+
+func (p *fakeProvider) SetModel(model string) {
+	p.model = model
+}
+
+func (p *fakeProvider) call(i int) []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[i]
+}
+
+func (p *fakeProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.calls)
+}
+
+func TestAgentToolLoop(t *testing.T) {
+	provider := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(ToolCall{Name: "weather", Arguments: `{"city":"Madrid"}`, ToolCallID: "call_1"}),
+		toolCalls(ToolCall{Name: "weather", Arguments: `{"city":"Lima"}`, ToolCallID: "call_2"}),
+		text("Sol en los dos"),
+	}}
+	calls := []string{}
+	agent := NewAgent("weather", "", withFake(provider), WithTools([]ToolInterface{weatherTool(&calls)}))
+	mem := NewSliceMemory(10)
+	mem.Add("user", "Madrid y Lima?")
+
+	response := agent.Run(context.Background(), NewBag[any](), mem)
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+	if response.Content != "Sol en los dos" || strings.Join(calls, ",") != "Madrid,Lima" {
+		t.Fatalf("content = %q, calls = %v", response.Content, calls)
+	}
+
+	// La ultima llamada ve las dos rondas de tools
+	last := provider.call(2)
+	roles := []string{}
+	for _, message := range last {
+		roles = append(roles, message.Role)
+	}
+	if got := strings.Join(roles, ","); got != "user,assistant,tool,assistant,tool" {
+		t.Fatalf("roles = %s", got)
+	}
+	if last[2].ToolCallID != "call_1" || last[4].ToolCallID != "call_2" {
+		t.Fatalf("tool messages = %+v", last)
+	}
+
+	// A la memoria solo vuelve la respuesta final
+	if all := mem.All(); len(all) != 2 || all[1].Content != "Sol en los dos" {
+		t.Fatalf("memory = %+v", all)
+	}
+}
+
+func TestAgentMaxIterations(t *testing.T) {
+	loop := toolCalls(ToolCall{Name: "weather", Arguments: `{"city":"Madrid"}`, ToolCallID: "call_1"})
+	provider := &fakeProvider{responses: []*ModelResponse{loop, loop, loop}}
+	calls := []string{}
+	agent := NewAgent("weather", "", withFake(provider),
+		WithTools([]ToolInterface{weatherTool(&calls)}),
+		WithMaxIterations(2),
+	)
+
+	response := agent.Run(context.Background(), NewBag[any](), NewSliceMemory(10))
+	if !errors.Is(response.Error, ErrMaxIterationsExceeded) {
+		t.Fatalf("err = %v, want ErrMaxIterationsExceeded", response.Error)
+	}
+	if provider.callCount() != 2 {
+		t.Fatalf("model called %d times, want 2", provider.callCount())
+	}
+}
+
+func TestAgentParallelTools(t *testing.T) {
+	provider := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(
+			ToolCall{Name: "slow", Arguments: `{"id":"a"}`, ToolCallID: "call_a"},
+			ToolCall{Name: "slow", Arguments: `{"id":"b"}`, ToolCallID: "call_b"},
+		),
+		text("listo"),
+	}}
+
+	// Cada llamada espera a la otra: solo terminan si corren a la vez
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	slow := NewTool("slow", "", []DescriptionParams{{Name: "id", Type: "string"}},
+		func(ctx context.Context, bag *Bag[any], input *ToolParams) (interface{}, error) {
+			arrived.Done()
+			done := make(chan struct{})
+			go func() { arrived.Wait(); close(done) }()
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				return nil, errors.New("tools did not run concurrently")
+			}
+			return "ok " + input.Params["id"].(string), nil
+		})
+	agent := NewAgent("parallel", "", withFake(provider), WithTools([]ToolInterface{slow}), WithParallelTools(2))
+
+	response := agent.Run(context.Background(), NewBag[any](), NewSliceMemory(10))
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+
+	// Los resultados vuelven en el orden de las llamadas
+	last := provider.call(1)
+	a, b := last[len(last)-2], last[len(last)-1]
+	if a.ToolCallID != "call_a" || a.Content != "ok a" || b.ToolCallID != "call_b" || b.Content != "ok b" {
+		t.Fatalf("tool messages = %+v, %+v", a, b)
+	}
+}
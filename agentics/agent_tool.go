@@ -0,0 +1,140 @@
+package agentics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const defaultToolMemorySize = 20
+
+// AgentTool expone un agente (o un SubGraph) como tool: el modelo que lo
+// llama recibe la respuesta del agente como resultado y sigue con el control,
+// a diferencia de un handoff.
+type AgentTool struct {
+	Name        string
+	Description string
+	Parameters  []DescriptionParams
+	Agent       AgentInterface
+	// Memory es la memoria que comparten todas las llamadas. Si es nil cada
+	// llamada arranca con una memoria vacia de MemorySize mensajes.
+	Memory     Memory
+	MemorySize int
+	Output     func(response AgentResponse) (interface{}, error)
+}
+
+type AgentToolOption func(*AgentTool)
+
+// WithToolParameters cambia el schema de entrada. Por defecto la tool recibe
+// un unico parametro "input"; con otros parametros el agente recibe los
+// argumentos como JSON.
+func WithToolParameters(params []DescriptionParams) AgentToolOption {
+	return func(t *AgentTool) {
+		t.Parameters = params
+	}
+}
+
+func WithToolMemory(mem Memory) AgentToolOption {
+	return func(t *AgentTool) {
+		t.Memory = mem
+	}
+}
+
+// WithToolMemorySize cambia cuantos mensajes guarda la memoria de cada
+// llamada cuando no hay WithToolMemory. Por defecto 20.
+func WithToolMemorySize(size int) AgentToolOption {
+	return func(t *AgentTool) {
+		t.MemorySize = size
+	}
+}
+
+// WithToolOutput define que parte de la respuesta vuelve al modelo. Por
+// defecto es Output si el agente tiene OutputType, si no Content.
+func WithToolOutput(output func(response AgentResponse) (interface{}, error)) AgentToolOption {
+	return func(t *AgentTool) {
+		t.Output = output
+	}
+}
+
+func NewAgentTool(name string, description string, agent AgentInterface, options ...AgentToolOption) ToolInterface {
+	tool := &AgentTool{
+		Name:        name,
+		Description: description,
+		Parameters: []DescriptionParams{
+			{Name: "input", Type: "string", Description: "The request for the agent.", Required: true},
+		},
+		Agent:      agent,
+		MemorySize: defaultToolMemorySize,
+	}
+
+	for _, option := range options {
+		option(tool)
+	}
+
+	return tool
+}
+
+func (t *AgentTool) GetName() string {
+	return t.Name
+}
+
+func (t *AgentTool) GetDescription() string {
+	return t.Description
+}
+
+func (t *AgentTool) GetParameters() []DescriptionParams {
+	return t.Parameters
+}
+
+func (t *AgentTool) Run(ctx context.Context, bag *Bag[any], input *ToolParams) (response *ToolResponse) {
+	defer recoverTool(t.Name, &response)
+
+	message, err := t.message(input)
+	if err != nil {
+		return toolError(err)
+	}
+
+	mem := t.Memory
+	if mem == nil {
+		size := t.MemorySize
+		if size <= 0 {
+			size = defaultToolMemorySize
+		}
+		mem = NewSliceMemory(size)
+	}
+	mem.Add("user", message)
+
+	result := t.Agent.Run(ctx, bag, mem)
+	if result.Error != nil {
+		return toolError(fmt.Errorf("agent %s: %w", t.Name, result.Error))
+	}
+
+	if t.Output != nil {
+		output, err := t.Output(result)
+		if err != nil {
+			return toolError(err)
+		}
+		return toolOutput(output)
+	}
+	if result.Output != nil {
+		return toolOutput(result.Output)
+	}
+	return toolOutput(result.Content)
+}
+
+func (t *AgentTool) message(input *ToolParams) (string, error) {
+	if len(t.Parameters) == 1 && t.Parameters[0].Name == "input" {
+		if value, ok := input.Params["input"].(string); ok {
+			return value, nil
+		}
+	}
+
+	if input.Arguments != "" {
+		return input.Arguments, nil
+	}
+	encoded, err := json.Marshal(input.Params)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,60 @@
+package agentics
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAgentTool(t *testing.T) {
+	specialist := &fakeProvider{responses: []*ModelResponse{text("Madrid tiene 3 millones")}}
+	manager := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(ToolCall{Name: "research", Arguments: `{"input":"poblacion de Madrid"}`, ToolCallID: "call_1"}),
+		text("Unos 3 millones"),
+	}}
+	research := NewAgentTool("research", "Research a topic.", NewAgent("researcher", "", withFake(specialist)))
+	agent := NewAgent("manager", "", withFake(manager), WithTools([]ToolInterface{research}))
+
+	mem := NewSliceMemory(10)
+	mem.Add("user", "Cuanta gente vive en Madrid?")
+	response := agent.Run(context.Background(), NewBag[any](), mem)
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+
+	// El especialista solo ve el pedido, no la conversacion del manager
+	if seen := specialist.call(0); len(seen) != 1 || seen[0].Content != "poblacion de Madrid" {
+		t.Fatalf("specialist messages = %+v", seen)
+	}
+	last := manager.call(1)
+	if result := last[len(last)-1]; result.Content != "Madrid tiene 3 millones" {
+		t.Fatalf("tool result = %+v", result)
+	}
+	if response.Content != "Unos 3 millones" {
+		t.Fatalf("content = %q", response.Content)
+	}
+}
+
+func TestAgentToolMemorySize(t *testing.T) {
+	var seen []Message
+	node := &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		for i := 0; i < 5; i++ {
+			mem.Add("assistant", "nota")
+		}
+		seen = mem.All()
+		return AgentResponse{Content: "ok"}
+	}}
+
+	tool := NewAgentTool("notes", "", node, WithToolMemorySize(3))
+	response := tool.Run(context.Background(), NewBag[any](), &ToolParams{Params: map[string]interface{}{"input": "hola"}})
+	if response.Error != nil || response.Output != "ok" {
+		t.Fatalf("response = %+v", response)
+	}
+	if len(seen) != 3 || strings.Contains(seen[0].Content, "hola") {
+		t.Fatalf("memory = %+v, want the last 3 messages", seen)
+	}
+
+	if tool.(*AgentTool).MemorySize != 3 || NewAgentTool("x", "", node).(*AgentTool).MemorySize != defaultToolMemorySize {
+		t.Fatal("memory size option not applied")
+	}
+}
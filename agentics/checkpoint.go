@@ -0,0 +1,312 @@
+package agentics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+	ErrInvalidRunID       = errors.New("invalid run id")
+)
+
+// Checkpoint es el estado de una corrida despues de un paso. Los valores del
+// Bag se guardan como JSON, asi que al reanudar un struct vuelve como
+// map[string]interface{}. MemorySession es la sesion de una memoria
+// persistente, que al reanudar se vuelve a abrir.
+type Checkpoint struct {
+	RunID         string                 `json:"run_id"`
+	Step          int                    `json:"step"`
+	Queue         []string               `json:"queue"`
+	Visits        map[string]int         `json:"visits"`
+	Bag           map[string]interface{} `json:"bag"`
+	Messages      []Message              `json:"messages"`
+	MemorySession string                 `json:"memory_session,omitempty"`
+	Done          bool                   `json:"done"`
+	Interrupt     *Interrupt             `json:"interrupt,omitempty"`
+}
+
+type Checkpointer interface {
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+	Load(ctx context.Context, runID string) (*Checkpoint, error)
+}
+
+func (g *Graph) SetCheckpointer(checkpointer Checkpointer) {
+	g.Checkpointer = checkpointer
+}
+
+func (g *Graph) checkpoint(ctx context.Context, cp *Checkpoint, bag *Bag[any], mem Memory) error {
+	if g.Checkpointer == nil {
+		return nil
+	}
+
+	cp.Bag = bag.All()
+	cp.Messages = mem.Snapshot()
+	cp.MemorySession = memorySessionID(mem)
+	if err := g.Checkpointer.Save(ctx, cp); err != nil {
+		return fmt.Errorf("checkpoint %s: %w", cp.RunID, err)
+	}
+	return nil
+}
+
+// Resume sigue una corrida desde su ultimo checkpoint, con un Bag y una
+// Memory nuevos armados a partir de lo guardado. Si la corrida ya termino no
+// corre ningun agente; si espera una respuesta humana usar ResumeWithAnswer.
+func (g *Graph) Resume(ctx context.Context, runID string) (*GraphResponse, error) {
+	return g.resume(ctx, runID, nil, nil)
+}
+
+func (g *Graph) ResumeStream(ctx context.Context, runID string, handler EventHandler) (*GraphResponse, error) {
+	return g.resume(ctx, runID, nil, syncHandler(handler))
+}
+
+func (c *CompiledGraph) Resume(ctx context.Context, runID string) (*GraphResponse, error) {
+	return c.graph.Resume(ctx, runID)
+}
+
+func (g *Graph) resume(ctx context.Context, runID string, answer *string, emit EventHandler) (*GraphResponse, error) {
+	if g.Checkpointer == nil {
+		return nil, errors.New("graph has no checkpointer")
+	}
+
+	cp, err := g.Checkpointer.Load(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Visits == nil {
+		cp.Visits = make(map[string]int)
+	}
+
+	bag := NewBag[any]()
+	for k, v := range cp.Bag {
+		bag.Set(k, v)
+	}
+	// La conversacion se reabre en la misma sesion del mismo backend
+	session := cp.MemorySession
+	if session == "" {
+		session = runID
+	}
+	mem, err := sessionMemory(g.Mem, session)
+	if err != nil {
+		return nil, err
+	}
+	mem.Restore(cp.Messages)
+
+	if cp.Interrupt != nil {
+		if answer == nil {
+			response := &GraphResponse{RunID: runID, Bag: bag, Mem: mem, Interrupt: cp.Interrupt}
+			return response, fmt.Errorf("%w: run %s is waiting for an answer", ErrInterrupted, runID)
+		}
+		setAnswer(bag, cp.Interrupt.Key, *answer)
+	}
+
+	ctx, saved := resumeInterrupt(ctx, cp)
+	return g.run(ctx, cp, bag, mem, emit, saved)
+}
+
+// resumeInterrupt deja en ctx lo que hace falta para seguir despues de
+// cp.Interrupt: la vuelta del agente que pregunto y la corrida del sub-grafo
+// si pregunto uno. Devuelve el ultimo paso guardado.
+func resumeInterrupt(ctx context.Context, cp *Checkpoint) (context.Context, int) {
+	if cp.Interrupt == nil {
+		return ctx, cp.Step
+	}
+
+	if cp.Interrupt.Turn != nil {
+		ctx = withTurn(ctx, cp.Interrupt.Turn)
+	}
+	if cp.Interrupt.Child != nil {
+		ctx = withChild(ctx, cp.Interrupt.Agent, cp.Interrupt.Child)
+	}
+	cp.Interrupt = nil
+	// Se vuelve a guardar para no perder la respuesta si hay un crash
+	return ctx, cp.Step - 1
+}
+
+// MemoryCheckpointer guarda los checkpoints en el proceso. Sirve para tests
+// o para reanudar despues de un error, no despues de un crash.
+type MemoryCheckpointer struct {
+	mu          sync.RWMutex
+	checkpoints map[string][]byte
+}
+
+func NewMemoryCheckpointer() *MemoryCheckpointer {
+	return &MemoryCheckpointer{
+		checkpoints: make(map[string][]byte),
+	}
+}
+
+func (c *MemoryCheckpointer) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkpoints[checkpoint.RunID] = data
+	return nil
+}
+
+func (c *MemoryCheckpointer) Load(ctx context.Context, runID string) (*Checkpoint, error) {
+	c.mu.RLock()
+	data, ok := c.checkpoints[runID]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, runID)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// FileCheckpointer guarda un archivo <run_id>.json por corrida en Dir.
+type FileCheckpointer struct {
+	Dir string
+}
+
+func NewFileCheckpointer(dir string) (*FileCheckpointer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCheckpointer{Dir: dir}, nil
+}
+
+func (c *FileCheckpointer) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	path, err := c.path(checkpoint.RunID)
+	if err != nil {
+		return err
+	}
+
+	// Se escribe a un temporal y se renombra para no dejar un archivo a medias
+	tmp, err := os.CreateTemp(c.Dir, checkpoint.RunID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (c *FileCheckpointer) Load(ctx context.Context, runID string) (*Checkpoint, error) {
+	path, err := c.path(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, runID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// path rechaza los ids que no son un nombre de archivo: con separadores dos
+// corridas distintas terminarian en el mismo archivo o fuera de Dir.
+func (c *FileCheckpointer) path(runID string) (string, error) {
+	if runID == "" || runID == "." || runID == ".." || strings.ContainsAny(runID, `/\`) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRunID, runID)
+	}
+	return filepath.Join(c.Dir, runID+".json"), nil
+}
+
+// SQLCheckpointer guarda los checkpoints en una tabla usando database/sql.
+// El driver lo elige quien lo usa (por ejemplo SQLite); las queries usan
+// placeholders "?".
+type SQLCheckpointer struct {
+	DB    *sql.DB
+	Table string
+}
+
+func NewSQLCheckpointer(ctx context.Context, db *sql.DB, table string) (*SQLCheckpointer, error) {
+	if table == "" {
+		table = "agentics_checkpoints"
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		run_id TEXT PRIMARY KEY,
+		step INTEGER NOT NULL,
+		data TEXT NOT NULL
+	)`, table)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return nil, err
+	}
+
+	return &SQLCheckpointer{DB: db, Table: table}, nil
+}
+
+func (c *SQLCheckpointer) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE run_id = ?", c.Table), checkpoint.RunID); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (run_id, step, data) VALUES (?, ?, ?)", c.Table)
+	if _, err := tx.ExecContext(ctx, query, checkpoint.RunID, checkpoint.Step, string(data)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (c *SQLCheckpointer) Load(ctx context.Context, runID string) (*Checkpoint, error) {
+	var data string
+	query := fmt.Sprintf("SELECT data FROM %s WHERE run_id = ?", c.Table)
+	err := c.DB.QueryRowContext(ctx, query, runID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, runID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal([]byte(data), &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
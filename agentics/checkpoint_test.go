@@ -0,0 +1,163 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func testCheckpointers(t *testing.T) map[string]Checkpointer {
+	file, err := NewFileCheckpointer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkpointers := map[string]Checkpointer{
+		"memory": NewMemoryCheckpointer(),
+		"file":   file,
+	}
+	if db := openTestDB(t); db != nil {
+		sqlCheckpointer, err := NewSQLCheckpointer(context.Background(), db, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkpointers["sql"] = sqlCheckpointer
+	}
+	return checkpointers
+}
+
+func TestCheckpointerRoundTrip(t *testing.T) {
+	for name, checkpointer := range testCheckpointers(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cp := &Checkpoint{
+				RunID:    "run-1",
+				Step:     2,
+				Queue:    []string{"b", "c"},
+				Visits:   map[string]int{"a": 1},
+				Bag:      map[string]interface{}{"city": "Madrid"},
+				Messages: []Message{{Role: "user", Content: "hola"}},
+			}
+			if err := checkpointer.Save(ctx, cp); err != nil {
+				t.Fatal(err)
+			}
+			// Guardar de nuevo reemplaza el checkpoint anterior
+			cp.Step = 3
+			if err := checkpointer.Save(ctx, cp); err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := checkpointer.Load(ctx, "run-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Step != 3 || len(loaded.Queue) != 2 || loaded.Visits["a"] != 1 ||
+				loaded.Bag["city"] != "Madrid" || loaded.Messages[0].Content != "hola" {
+				t.Fatalf("loaded = %+v", loaded)
+			}
+
+			if _, err := checkpointer.Load(ctx, "missing"); !errors.Is(err, ErrCheckpointNotFound) {
+				t.Fatalf("err = %v, want ErrCheckpointNotFound", err)
+			}
+		})
+	}
+}
+
+func TestGraphResume(t *testing.T) {
+	for name, checkpointer := range testCheckpointers(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			a := &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+				bag.Set("city", "Madrid")
+				mem.Add("assistant", "uno")
+				return AgentResponse{Content: "uno"}
+			}}
+			fail := true
+			b := &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+				if fail {
+					return AgentResponse{Error: errors.New("caido")}
+				}
+				mem.Add("assistant", "dos "+bag.Get("city").(string))
+				return AgentResponse{Content: "dos"}
+			}}
+			c := reply("tres")
+
+			g := NewGraph(NewBag[any](), NewSliceMemory(10))
+			g.AddNode("a", a)
+			g.AddNode("b", b)
+			g.AddNode("c", c)
+			g.AddRelation(Entrypoint, "a")
+			g.AddRelation("a", "b")
+			g.AddRelation("b", "c")
+			g.SetCheckpointer(checkpointer)
+
+			if _, err := g.Run(ctx, WithRunID("run-resume")); err == nil {
+				t.Fatal("expected b to fail")
+			}
+
+			fail = false
+			response, err := g.Resume(ctx, "run-resume")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(response.Results); got != "b,c" {
+				t.Fatalf("results = %s, want b,c", got)
+			}
+			if a.count() != 1 {
+				t.Fatalf("a ran %d times, want 1", a.count())
+			}
+
+			// El Bag y la memoria vuelven del checkpoint
+			all := response.Mem.All()
+			if len(all) != 3 || all[0].Content != "uno" || all[1].Content != "dos Madrid" {
+				t.Fatalf("memory = %+v", all)
+			}
+
+			// Una corrida terminada no vuelve a correr nada
+			response, err = g.Resume(ctx, "run-resume")
+			if err != nil || len(response.Results) != 0 {
+				t.Fatalf("results = %v, err = %v", response.Results, err)
+			}
+		})
+	}
+}
+
+func TestWithRunID(t *testing.T) {
+	checkpointer := NewMemoryCheckpointer()
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("a", reply("uno"))
+	g.SetEntrypoint("a")
+	g.SetCheckpointer(checkpointer)
+
+	response, err := g.Run(context.Background(), WithRunID("elegido"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.RunID != "elegido" {
+		t.Fatalf("run id = %q", response.RunID)
+	}
+	if cp, err := checkpointer.Load(context.Background(), "elegido"); err != nil || !cp.Done {
+		t.Fatalf("checkpoint = %+v, err = %v", cp, err)
+	}
+}
+
+func TestFileCheckpointerRunIDs(t *testing.T) {
+	checkpointer, err := NewFileCheckpointer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// "a/x" y "b/x" no pueden terminar en el mismo archivo x.json
+	for _, runID := range []string{"a/x", `b\x`, "..", ""} {
+		if err := checkpointer.Save(ctx, &Checkpoint{RunID: runID}); !errors.Is(err, ErrInvalidRunID) {
+			t.Errorf("save %q: err = %v, want ErrInvalidRunID", runID, err)
+		}
+		if _, err := checkpointer.Load(ctx, runID); !errors.Is(err, ErrInvalidRunID) {
+			t.Errorf("load %q: err = %v, want ErrInvalidRunID", runID, err)
+		}
+	}
+	if err := checkpointer.Save(ctx, &Checkpoint{RunID: "run-1.v2"}); err != nil {
+		t.Fatal(err)
+	}
+}
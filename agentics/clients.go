@@ -2,12 +2,18 @@ package agentics
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	anthropics_option "github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/openai/openai-go"
 	openai_option "github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
 )
 
 type ModelType string
@@ -22,8 +28,8 @@ type ModelClient struct {
 }
 
 type ModelProvider interface {
-	Execute(ctx context.Context, prompt string, messages []Message, tools []ToolInterface) (*ModelResponse, error)
-	ExecuteWithFollowUp(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, toolResult string) (*ModelResponse, error)
+	Execute(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, output *OutputSchema) (*ModelResponse, error)
+	ExecuteStream(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, output *OutputSchema, onDelta func(string)) (*ModelResponse, error)
 	GetModel() string
 	SetModel(model string)
 }
@@ -36,9 +42,9 @@ type ModelResponse struct {
 }
 
 type ToolCall struct {
-	Name       string
-	Arguments  string
-	ToolCallID string
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments"`
+	ToolCallID string `json:"tool_call_id"`
 }
 
 func (r *ModelResponse) GetContent() string {
@@ -67,44 +73,65 @@ func (p *OpenAIProvider) SetModel(model string) {
 	p.Model = model
 }
 
-func (p *OpenAIProvider) ExecuteWithFollowUp(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, toolResult string) (*ModelResponse, error) {
-	openAIMessages := p.toOpenAIMessages(messages)
-	newMessages := []openai.ChatCompletionMessageParamUnion{}
-	newMessages = append(newMessages, openai.SystemMessage(prompt))
-	newMessages = append(newMessages, openAIMessages...)
-	newMessages = append(newMessages, openai.UserMessage(toolResult))
-
-	chatCompletion, err := p.Client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: newMessages,
-		Model:    openai.ChatModel(p.Model),
-		Tools:    p.getOpenAITools(tools),
-	})
+func (p *OpenAIProvider) Execute(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, output *OutputSchema) (*ModelResponse, error) {
+	chatCompletion, err := p.Client.Chat.Completions.New(ctx, p.newParams(prompt, messages, tools, output))
 	if err != nil {
 		return nil, err
 	}
 
-	params, _ := chatCompletion.Choices[0].Message.ToParam().MarshalJSON()
+	return p.toModelResponse(chatCompletion)
+}
 
-	return &ModelResponse{
-		IsToolCall: chatCompletion.Choices[0].FinishReason == "tool_calls",
-		ToolCalls:  p.getToolCalls(chatCompletion.Choices[0].Message.ToolCalls),
-		Content:    chatCompletion.Choices[0].Message.Content,
-		Params:     params,
-	}, nil
+func (p *OpenAIProvider) ExecuteStream(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, output *OutputSchema, onDelta func(string)) (*ModelResponse, error) {
+	stream := p.Client.Chat.Completions.NewStreaming(ctx, p.newParams(prompt, messages, tools, output))
+	defer stream.Close()
+
+	acc := openai.ChatCompletionAccumulator{}
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" && onDelta != nil {
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+
+	return p.toModelResponse(&acc.ChatCompletion)
 }
 
-func (p *OpenAIProvider) Execute(ctx context.Context, prompt string, messages []Message, tools []ToolInterface) (*ModelResponse, error) {
+func (p *OpenAIProvider) newParams(prompt string, messages []Message, tools []ToolInterface, output *OutputSchema) openai.ChatCompletionNewParams {
 	openAIMessages := p.toOpenAIMessages(messages)
 	newMessages := []openai.ChatCompletionMessageParamUnion{}
 	newMessages = append(newMessages, openai.SystemMessage(prompt))
 	newMessages = append(newMessages, openAIMessages...)
-	chatCompletion, err := p.Client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+
+	params := openai.ChatCompletionNewParams{
 		Messages: newMessages,
 		Model:    openai.ChatModel(p.Model),
-		Tools:    p.getOpenAITools(tools),
-	})
-	if err != nil {
-		return nil, err
+	}
+	if len(tools) > 0 {
+		params.Tools = p.getOpenAITools(tools)
+	}
+	if output != nil {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   output.Name,
+					Schema: output.Schema,
+				},
+			},
+		}
+	}
+
+	return params
+}
+
+func (p *OpenAIProvider) toModelResponse(chatCompletion *openai.ChatCompletion) (*ModelResponse, error) {
+	if len(chatCompletion.Choices) == 0 {
+		return nil, errors.New("openai: empty response")
 	}
 
 	params, _ := chatCompletion.Choices[0].Message.ToParam().MarshalJSON()
@@ -134,22 +161,11 @@ func (p *OpenAIProvider) getToolCalls(toolCalls []openai.ChatCompletionMessageTo
 func (p *OpenAIProvider) getOpenAITools(tools []ToolInterface) []openai.ChatCompletionToolParam {
 	result := []openai.ChatCompletionToolParam{}
 	for _, tool := range tools {
-		properties := make(map[string]interface{})
-
-		for _, param := range tool.GetParameters() {
-			properties[param.Name] = map[string]string{
-				"type": param.Type,
-			}
-		}
-
 		result = append(result, openai.ChatCompletionToolParam{
 			Function: openai.FunctionDefinitionParam{
 				Name:        tool.GetName(),
 				Description: openai.String(tool.GetDescription()),
-				Parameters: openai.FunctionParameters{
-					"type":       "object",
-					"properties": properties,
-				},
+				Parameters:  openai.FunctionParameters(ParametersSchema(tool.GetParameters())),
 			},
 		})
 	}
@@ -163,15 +179,47 @@ func (p *OpenAIProvider) toOpenAIMessages(m []Message) []openai.ChatCompletionMe
 	for i, message := range m {
 		switch message.Role {
 		case "tool":
-			if i == 0 || m[i-1].Role != "assistant" {
+			if i == 0 || (m[i-1].Role != "assistant" && m[i-1].Role != "tool") {
 				result = append(result, openai.AssistantMessage(""))
 			}
-			result = append(result, openai.ToolMessage(message.Content, message.ToolCallID))
+			content := message.Content
+			if message.IsError {
+				content = "Error: " + content
+			}
+			result = append(result, openai.ToolMessage(content, message.ToolCallID))
 		case "user":
-			result = append(result, openai.UserMessage(message.Content))
+			if len(message.Data) == 0 {
+				result = append(result, openai.UserMessage(message.Content))
+				continue
+			}
+			parts := []openai.ChatCompletionContentPartUnionParam{}
+			if message.Content != "" {
+				parts = append(parts, openai.TextContentPart(message.Content))
+			}
+			if isImage(message) {
+				parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+					URL: "data:" + message.MediaType + ";base64," + base64.StdEncoding.EncodeToString(message.Data),
+				}))
+			} else {
+				parts = append(parts, openai.TextContentPart(binaryPlaceholder(message)))
+			}
+			result = append(result, openai.UserMessage(parts))
 		case "assistant":
 			if len(message.ToolCalls) > 0 {
-				result = append(result, openai.AssistantMessage(message.Content))
+				assistant := openai.ChatCompletionAssistantMessageParam{}
+				if message.Content != "" {
+					assistant.Content.OfString = openai.String(message.Content)
+				}
+				for _, toolCall := range message.ToolCalls {
+					assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallParam{
+						ID: toolCall.ToolCallID,
+						Function: openai.ChatCompletionMessageToolCallFunctionParam{
+							Name:      toolCall.Name,
+							Arguments: toolCall.Arguments,
+						},
+					})
+				}
+				result = append(result, openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant})
 			} else {
 				result = append(result, openai.AssistantMessage(message.Content))
 			}
@@ -186,8 +234,9 @@ func (p *OpenAIProvider) toOpenAIMessages(m []Message) []openai.ChatCompletionMe
 }
 
 type AnthropicProvider struct {
-	Client *anthropic.Client
-	Model  string
+	Client    *anthropic.Client
+	Model     string
+	MaxTokens int64
 }
 
 func NewAnthropicProvider() *AnthropicProvider {
@@ -195,7 +244,8 @@ func NewAnthropicProvider() *AnthropicProvider {
 		Client: anthropic.NewClient(
 			anthropics_option.WithAPIKey(os.Getenv("ANTHROPIC_API_KEY")),
 		),
-		Model: "claude-3-sonnet-20240229",
+		Model:     "claude-3-sonnet-20240229",
+		MaxTokens: 1024,
 	}
 }
 
@@ -207,14 +257,201 @@ func (p *AnthropicProvider) SetModel(model string) {
 	p.Model = model
 }
 
-func (p *AnthropicProvider) Execute(ctx context.Context, prompt string, messages []Message, tools []ToolInterface) (*ModelResponse, error) {
-	// TODO: Implementar para Anthropic
-	return nil, nil
+func (p *AnthropicProvider) Execute(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, output *OutputSchema) (*ModelResponse, error) {
+	message, err := p.Client.Messages.New(ctx, p.newParams(prompt, messages, tools, output))
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toModelResponse(message, output), nil
+}
+
+func (p *AnthropicProvider) ExecuteStream(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, output *OutputSchema, onDelta func(string)) (*ModelResponse, error) {
+	stream := p.Client.Messages.NewStreaming(ctx, p.newParams(prompt, messages, tools, output))
+	defer stream.Close()
+
+	message := anthropic.Message{}
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, err
+		}
+
+		if delta, ok := event.AsUnion().(anthropic.ContentBlockDeltaEvent); ok && delta.Delta.Text != "" && onDelta != nil {
+			onDelta(delta.Delta.Text)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+
+	return p.toModelResponse(&message, output), nil
+}
+
+func (p *AnthropicProvider) newParams(prompt string, messages []Message, tools []ToolInterface, output *OutputSchema) anthropic.MessageNewParams {
+	system, anthropicMessages := p.toAnthropicMessages(prompt, messages)
+
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.F(anthropic.Model(p.Model)),
+		MaxTokens: anthropic.F(p.MaxTokens),
+		Messages:  anthropic.F(anthropicMessages),
+	}
+	if len(system) > 0 {
+		params.System = anthropic.F(system)
+	}
+	anthropicTools := p.getAnthropicTools(tools)
+	if output != nil {
+		// Anthropic no tiene response_format, se fuerza una tool con el schema
+		anthropicTools = append(anthropicTools, anthropic.ToolParam{
+			Name:        anthropic.F(output.Name),
+			Description: anthropic.F("Respond with the final answer using this structure."),
+			InputSchema: anthropic.F[interface{}](output.Schema),
+		})
+		if len(tools) > 0 {
+			params.ToolChoice = anthropic.F[anthropic.ToolChoiceUnionParam](anthropic.ToolChoiceAnyParam{
+				Type: anthropic.F(anthropic.ToolChoiceAnyTypeAny),
+			})
+		} else {
+			params.ToolChoice = anthropic.F[anthropic.ToolChoiceUnionParam](anthropic.ToolChoiceToolParam{
+				Type: anthropic.F(anthropic.ToolChoiceToolTypeTool),
+				Name: anthropic.F(output.Name),
+			})
+		}
+	}
+	if len(anthropicTools) > 0 {
+		params.Tools = anthropic.F(anthropicTools)
+	}
+
+	return params
+}
+
+func (p *AnthropicProvider) toModelResponse(message *anthropic.Message, output *OutputSchema) *ModelResponse {
+	content := ""
+	structured := ""
+	toolCalls := []ToolCall{}
+	for _, block := range message.Content {
+		switch block.Type {
+		case anthropic.ContentBlockTypeText:
+			content += block.Text
+		case anthropic.ContentBlockTypeToolUse:
+			if output != nil && block.Name == output.Name {
+				// La respuesta estructurada llega como input de la tool forzada
+				structured = string(block.Input)
+				continue
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				Name:       block.Name,
+				Arguments:  string(block.Input),
+				ToolCallID: block.ID,
+			})
+		}
+	}
+
+	raw, _ := json.Marshal(message.ToParam())
+
+	// Si en el mismo turno hay tools reales se ejecutan primero; la respuesta
+	// estructurada se vuelve a pedir en el turno final.
+	if structured != "" && len(toolCalls) == 0 {
+		return &ModelResponse{
+			Content: structured,
+			Params:  raw,
+		}
+	}
+
+	return &ModelResponse{
+		IsToolCall: message.StopReason == anthropic.MessageStopReasonToolUse,
+		ToolCalls:  toolCalls,
+		Content:    content,
+		Params:     raw,
+	}
+}
+
+func (p *AnthropicProvider) getAnthropicTools(tools []ToolInterface) []anthropic.ToolUnionUnionParam {
+	result := []anthropic.ToolUnionUnionParam{}
+	for _, tool := range tools {
+		result = append(result, anthropic.ToolParam{
+			Name:        anthropic.F(tool.GetName()),
+			Description: anthropic.F(tool.GetDescription()),
+			InputSchema: anthropic.F[interface{}](ParametersSchema(tool.GetParameters())),
+		})
+	}
+
+	return result
 }
 
-func (p *AnthropicProvider) ExecuteWithFollowUp(ctx context.Context, prompt string, messages []Message, tools []ToolInterface, toolResult string) (*ModelResponse, error) {
-	// TODO: Implementar para Anthropic
-	return nil, nil
+// Anthropic no acepta mensajes "system" dentro de la conversacion ni dos turnos
+// seguidos del mismo rol, asi que se mueven al system prompt y se agrupan.
+func (p *AnthropicProvider) toAnthropicMessages(prompt string, m []Message) ([]anthropic.TextBlockParam, []anthropic.MessageParam) {
+	system := []anthropic.TextBlockParam{}
+	if prompt != "" {
+		system = append(system, anthropic.NewTextBlock(prompt))
+	}
+
+	result := []anthropic.MessageParam{}
+	appendBlocks := func(role anthropic.MessageParamRole, blocks ...anthropic.ContentBlockParamUnion) {
+		if len(blocks) == 0 {
+			return
+		}
+		if len(result) > 0 && result[len(result)-1].Role.Value == role {
+			last := &result[len(result)-1]
+			last.Content = anthropic.F(append(last.Content.Value, blocks...))
+			return
+		}
+		result = append(result, anthropic.MessageParam{
+			Role:    anthropic.F(role),
+			Content: anthropic.F(blocks),
+		})
+	}
+
+	for _, message := range m {
+		switch message.Role {
+		case "system":
+			if message.Content != "" {
+				system = append(system, anthropic.NewTextBlock(message.Content))
+			}
+		case "user":
+			if message.Content != "" {
+				appendBlocks(anthropic.MessageParamRoleUser, anthropic.NewTextBlock(message.Content))
+			}
+			if isImage(message) {
+				appendBlocks(anthropic.MessageParamRoleUser, anthropic.NewImageBlockBase64(message.MediaType, base64.StdEncoding.EncodeToString(message.Data)))
+			} else if len(message.Data) > 0 {
+				appendBlocks(anthropic.MessageParamRoleUser, anthropic.NewTextBlock(binaryPlaceholder(message)))
+			}
+		case "assistant":
+			blocks := []anthropic.ContentBlockParamUnion{}
+			if message.Content != "" {
+				blocks = append(blocks, anthropic.NewTextBlock(message.Content))
+			}
+			for _, toolCall := range message.ToolCalls {
+				blocks = append(blocks, anthropic.NewToolUseBlockParam(toolCall.ToolCallID, toolCall.Name, toolArguments(toolCall.Arguments)))
+			}
+			appendBlocks(anthropic.MessageParamRoleAssistant, blocks...)
+		case "tool":
+			appendBlocks(anthropic.MessageParamRoleUser, anthropic.NewToolResultBlock(message.ToolCallID, message.Content, message.IsError))
+		case "assistant_tool":
+			continue
+		}
+	}
+
+	return system, result
+}
+
+func isImage(message Message) bool {
+	return len(message.Data) > 0 && strings.HasPrefix(message.MediaType, "image/")
+}
+
+// Los proveedores solo reciben imagenes; el resto del contenido binario se
+// anuncia como texto para que el modelo sepa que existe.
+func binaryPlaceholder(message Message) string {
+	return fmt.Sprintf("[binary content: %s, %d bytes]", message.MediaType, len(message.Data))
+}
+
+func toolArguments(arguments string) json.RawMessage {
+	if arguments == "" || !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
 }
 
 func NewModelClient(modelType ModelType) *ModelClient {
@@ -0,0 +1,390 @@
+package agentics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropics_option "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/openai/openai-go"
+	openai_option "github.com/openai/openai-go/option"
+)
+
+// fakeAPI responde en orden las respuestas cargadas y guarda el body de cada
+// request para revisar que mando el proveedor.
+type fakeAPI struct {
+	mu        sync.Mutex
+	status    int
+	responses []string
+	requests  []map[string]interface{}
+}
+
+func newFakeAPI(t *testing.T, responses ...string) (*fakeAPI, *httptest.Server) {
+	api := &fakeAPI{status: http.StatusOK, responses: responses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var request map[string]interface{}
+		json.Unmarshal(body, &request)
+
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		api.requests = append(api.requests, request)
+		if len(api.responses) == 0 {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		response := api.responses[0]
+		api.responses = api.responses[1:]
+
+		// Las respuestas armadas con sse se mandan como stream
+		if strings.HasPrefix(response, "event:") || strings.HasPrefix(response, "data:") {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(api.status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+
+	return api, server
+}
+
+func (a *fakeAPI) request(i int) map[string]interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.requests[i]
+}
+
+func newTestOpenAIProvider(url string) *OpenAIProvider {
+	return &OpenAIProvider{
+		Client: openai.NewClient(
+			openai_option.WithBaseURL(url+"/"),
+			openai_option.WithAPIKey("test"),
+			openai_option.WithMaxRetries(0),
+		),
+		Model: "gpt-4o",
+	}
+}
+
+func newTestAnthropicProvider(url string) *AnthropicProvider {
+	return &AnthropicProvider{
+		Client: anthropic.NewClient(
+			anthropics_option.WithBaseURL(url+"/"),
+			anthropics_option.WithAPIKey("test"),
+			anthropics_option.WithMaxRetries(0),
+		),
+		Model:     "claude-3-5-sonnet-latest",
+		MaxTokens: 1024,
+	}
+}
+
+func weatherTool(calls *[]string) ToolInterface {
+	return NewTool("weather", "Get the weather", []DescriptionParams{
+		{Name: "city", Type: "string", Required: true},
+	}, func(ctx context.Context, bag *Bag[any], input *ToolParams) (interface{}, error) {
+		*calls = append(*calls, input.Params["city"].(string))
+		return "soleado", nil
+	})
+}
+
+const openAIText = `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o",
+	"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Hace sol"}}]}`
+
+const openAIToolCall = `{"id":"chatcmpl-2","object":"chat.completion","created":1,"model":"gpt-4o",
+	"choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":null,
+	"tool_calls":[{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Madrid\"}"}}]}}]}`
+
+func TestOpenAIProviderExecute(t *testing.T) {
+	api, server := newFakeAPI(t, openAIText)
+	provider := newTestOpenAIProvider(server.URL)
+
+	response, err := provider.Execute(context.Background(), "Sos un asistente", []Message{
+		{Role: "user", Content: "hola"},
+	}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Content != "Hace sol" || response.IsToolCall {
+		t.Fatalf("response = %+v", response)
+	}
+
+	messages := api.request(0)["messages"].([]interface{})
+	if len(messages) != 2 {
+		t.Fatalf("messages = %v, want system prompt and user message", messages)
+	}
+	if system := messages[0].(map[string]interface{}); system["role"] != "system" || system["content"] != "Sos un asistente" {
+		t.Fatalf("system message = %v", system)
+	}
+}
+
+func TestOpenAIProviderToolRoundTrip(t *testing.T) {
+	api, server := newFakeAPI(t, openAIToolCall, openAIText)
+	calls := []string{}
+	agent := NewAgent("weather", "Answer about the weather",
+		WithClient(ModelClient{provider: newTestOpenAIProvider(server.URL)}),
+		WithTools([]ToolInterface{weatherTool(&calls)}),
+	)
+	mem := NewSliceMemory(10)
+	mem.Add("user", "Que tiempo hace en Madrid?")
+
+	response := agent.Run(context.Background(), NewBag[any](), mem)
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+	if response.Content != "Hace sol" {
+		t.Fatalf("content = %q", response.Content)
+	}
+	if len(calls) != 1 || calls[0] != "Madrid" {
+		t.Fatalf("tool calls = %v", calls)
+	}
+
+	if tools := api.request(0)["tools"].([]interface{}); len(tools) != 1 {
+		t.Fatalf("tools = %v", tools)
+	}
+	messages := api.request(1)["messages"].([]interface{})
+	assistant := messages[len(messages)-2].(map[string]interface{})
+	if toolCalls, _ := assistant["tool_calls"].([]interface{}); len(toolCalls) != 1 {
+		t.Fatalf("assistant message = %v, want the tool call", assistant)
+	}
+	tool := messages[len(messages)-1].(map[string]interface{})
+	if tool["role"] != "tool" || tool["tool_call_id"] != "call_1" || tool["content"] != "soleado" {
+		t.Fatalf("tool message = %v", tool)
+	}
+}
+
+func TestOpenAIProviderErrors(t *testing.T) {
+	api, server := newFakeAPI(t, `{"error":{"message":"invalid model","type":"invalid_request_error"}}`)
+	api.status = http.StatusBadRequest
+	provider := newTestOpenAIProvider(server.URL)
+
+	if _, err := provider.Execute(context.Background(), "", []Message{{Role: "user", Content: "hola"}}, nil, nil); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("err = %v, want the 400 from the API", err)
+	}
+
+	_, server = newFakeAPI(t, `{"id":"chatcmpl-3","object":"chat.completion","created":1,"model":"gpt-4o","choices":[]}`)
+	provider = newTestOpenAIProvider(server.URL)
+	if _, err := provider.Execute(context.Background(), "", []Message{{Role: "user", Content: "hola"}}, nil, nil); err == nil {
+		t.Fatal("expected an error for a response without choices")
+	}
+}
+
+const anthropicText = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet-latest",
+	"content":[{"type":"text","text":"Hace sol"}],"stop_reason":"end_turn","stop_sequence":null,
+	"usage":{"input_tokens":1,"output_tokens":1}}`
+
+const anthropicToolUse = `{"id":"msg_2","type":"message","role":"assistant","model":"claude-3-5-sonnet-latest",
+	"content":[{"type":"text","text":"Busco el clima."},{"type":"tool_use","id":"toolu_1","name":"weather","input":{"city":"Madrid"}}],
+	"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":1,"output_tokens":1}}`
+
+func TestAnthropicProviderExecute(t *testing.T) {
+	api, server := newFakeAPI(t, anthropicText)
+	provider := newTestAnthropicProvider(server.URL)
+
+	response, err := provider.Execute(context.Background(), "Sos un asistente", []Message{
+		{Role: "system", Content: "El usuario se llama Ana"},
+		{Role: "user", Content: "hola"},
+	}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Content != "Hace sol" || response.IsToolCall {
+		t.Fatalf("response = %+v", response)
+	}
+
+	// Los mensajes de sistema van al system prompt, no a la conversacion
+	request := api.request(0)
+	if system := request["system"].([]interface{}); len(system) != 2 {
+		t.Fatalf("system = %v", system)
+	}
+	if messages := request["messages"].([]interface{}); len(messages) != 1 {
+		t.Fatalf("messages = %v", messages)
+	}
+}
+
+func TestAnthropicProviderToolRoundTrip(t *testing.T) {
+	api, server := newFakeAPI(t, anthropicToolUse, anthropicText)
+	calls := []string{}
+	agent := NewAgent("weather", "Answer about the weather",
+		WithClient(ModelClient{provider: newTestAnthropicProvider(server.URL)}),
+		WithTools([]ToolInterface{weatherTool(&calls)}),
+	)
+	mem := NewSliceMemory(10)
+	mem.Add("user", "Que tiempo hace en Madrid?")
+
+	response := agent.Run(context.Background(), NewBag[any](), mem)
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+	if response.Content != "Hace sol" {
+		t.Fatalf("content = %q", response.Content)
+	}
+	if len(calls) != 1 || calls[0] != "Madrid" {
+		t.Fatalf("tool calls = %v", calls)
+	}
+
+	messages := api.request(1)["messages"].([]interface{})
+	if len(messages) != 3 {
+		t.Fatalf("messages = %v, want user, assistant tool_use and user tool_result", messages)
+	}
+	result := messages[2].(map[string]interface{})
+	block := result["content"].([]interface{})[0].(map[string]interface{})
+	if result["role"] != "user" || block["type"] != "tool_result" || block["tool_use_id"] != "toolu_1" {
+		t.Fatalf("tool result = %v", result)
+	}
+}
+
+func TestAnthropicProviderErrors(t *testing.T) {
+	api, server := newFakeAPI(t, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: too large"}}`)
+	api.status = http.StatusBadRequest
+	provider := newTestAnthropicProvider(server.URL)
+
+	_, err := provider.Execute(context.Background(), "", []Message{{Role: "user", Content: "hola"}}, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("err = %v, want the 400 from the API", err)
+	}
+}
+
+func TestAnthropicStructuredOutputWithToolCalls(t *testing.T) {
+	mixed := `{"id":"msg_3","type":"message","role":"assistant","model":"claude-3-5-sonnet-latest",
+		"content":[{"type":"tool_use","id":"toolu_1","name":"weather","input":{"city":"Madrid"}},
+		{"type":"tool_use","id":"toolu_2","name":"forecastoutput","input":{"city":"Madrid","days":1}}],
+		"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":1,"output_tokens":1}}`
+	final := `{"id":"msg_4","type":"message","role":"assistant","model":"claude-3-5-sonnet-latest",
+		"content":[{"type":"tool_use","id":"toolu_3","name":"forecastoutput","input":{"city":"Madrid","days":3}}],
+		"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":1,"output_tokens":1}}`
+	_, server := newFakeAPI(t, mixed, final)
+
+	calls := []string{}
+	agent := NewAgent("weather", "Answer about the weather",
+		WithClient(ModelClient{provider: newTestAnthropicProvider(server.URL)}),
+		WithTools([]ToolInterface{weatherTool(&calls)}),
+		WithOutputType(OutputOf[forecastOutput]()),
+	)
+
+	response := agent.Run(context.Background(), NewBag[any](), NewSliceMemory(10))
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("weather tool ran %d times, want 1", len(calls))
+	}
+	if output, ok := response.Output.(forecastOutput); !ok || output.Days != 3 {
+		t.Fatalf("output = %#v, want the answer from the final turn", response.Output)
+	}
+}
+
+// sse arma el body de un stream con un evento por bloque: "data: ..." o
+// "event: ...\ndata: ...".
+func sse(events ...string) string {
+	return strings.Join(events, "\n\n") + "\n\n"
+}
+
+func TestOpenAIProviderExecuteStream(t *testing.T) {
+	chunk := `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":%s}`
+	choice := func(delta string, finish string) string {
+		return fmt.Sprintf(chunk, `[{"index":0,"delta":`+delta+`,"finish_reason":`+finish+`}]`)
+	}
+	// El ultimo chunk trae el uso y ninguna choice
+	usage := fmt.Sprintf(chunk, `[],"usage":{"prompt_tokens":10,"completion_tokens":4,"total_tokens":14}`)
+
+	textStream := sse(
+		choice(`{"role":"assistant","content":""}`, "null"),
+		choice(`{"content":"Hace"}`, "null"),
+		choice(`{"content":" sol"}`, "null"),
+		choice(`{}`, `"stop"`),
+		usage,
+		"data: [DONE]",
+	)
+	toolStream := sse(
+		choice(`{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"weather","arguments":""}}]}`, "null"),
+		choice(`{"tool_calls":[{"index":0,"function":{"arguments":"{\"ci"}}]}`, "null"),
+		choice(`{"tool_calls":[{"index":0,"function":{"arguments":"ty\":\"Madrid\"}"}}]}`, "null"),
+		choice(`{}`, `"tool_calls"`),
+		usage,
+		"data: [DONE]",
+	)
+	api, server := newFakeAPI(t, textStream, toolStream)
+	provider := newTestOpenAIProvider(server.URL)
+	messages := []Message{{Role: "user", Content: "hola"}}
+
+	deltas := []string{}
+	response, err := provider.ExecuteStream(context.Background(), "", messages, nil, nil, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(deltas, "|") != "Hace| sol" || response.Content != "Hace sol" || response.IsToolCall {
+		t.Fatalf("deltas = %q, response = %+v", deltas, response)
+	}
+	if api.request(0)["stream"] != true {
+		t.Fatalf("request = %v, want stream", api.request(0))
+	}
+
+	// Los argumentos de la tool llegan en pedazos y se juntan
+	deltas = nil
+	response, err = provider.ExecuteStream(context.Background(), "", messages, []ToolInterface{weatherTool(&[]string{})}, nil, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.IsToolCall || len(response.ToolCalls) != 1 || len(deltas) != 0 {
+		t.Fatalf("deltas = %q, response = %+v", deltas, response)
+	}
+	if call := response.ToolCalls[0]; call.Name != "weather" || call.ToolCallID != "call_1" || call.Arguments != `{"city":"Madrid"}` {
+		t.Fatalf("tool call = %+v", call)
+	}
+}
+
+func TestAnthropicProviderExecuteStream(t *testing.T) {
+	event := func(name string, data string) string {
+		return "event: " + name + "\ndata: " + data
+	}
+	stream := sse(
+		event("message_start", `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet-latest","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":1}}}`),
+		event("content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`),
+		event("ping", `{"type":"ping"}`),
+		event("content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Veo el"}}`),
+		event("content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" clima"}}`),
+		event("content_block_stop", `{"type":"content_block_stop","index":0}`),
+		event("content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"weather","input":{}}}`),
+		event("content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"ci"}}`),
+		event("content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"ty\":\"Madrid\"}"}}`),
+		event("content_block_stop", `{"type":"content_block_stop","index":1}`),
+		event("message_delta", `{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":12}}`),
+		event("message_stop", `{"type":"message_stop"}`),
+	)
+	api, server := newFakeAPI(t, stream)
+	provider := newTestAnthropicProvider(server.URL)
+
+	deltas := []string{}
+	response, err := provider.ExecuteStream(context.Background(), "", []Message{{Role: "user", Content: "clima en Madrid?"}}, []ToolInterface{weatherTool(&[]string{})}, nil, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(deltas, "|") != "Veo el| clima" || response.Content != "Veo el clima" {
+		t.Fatalf("deltas = %q, response = %+v", deltas, response)
+	}
+	// stop_reason llega en message_delta, al final del stream
+	if !response.IsToolCall || len(response.ToolCalls) != 1 {
+		t.Fatalf("response = %+v", response)
+	}
+	if call := response.ToolCalls[0]; call.Name != "weather" || call.ToolCallID != "toolu_1" || call.Arguments != `{"city":"Madrid"}` {
+		t.Fatalf("tool call = %+v", call)
+	}
+	if api.request(0)["stream"] != true {
+		t.Fatalf("request = %v, want stream", api.request(0))
+	}
+}
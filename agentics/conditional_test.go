@@ -0,0 +1,86 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestConditionalEdge(t *testing.T) {
+	route := func(bag *Bag[any], response AgentResponse) string {
+		if response.Content == "factura" {
+			return "billing"
+		}
+		return ""
+	}
+
+	for _, tc := range []struct {
+		content string
+		want    string
+	}{
+		{"factura", "triage,billing"},
+		// Con "" sigue por las relaciones normales
+		{"otra cosa", "triage,support"},
+	} {
+		g := NewGraph(NewBag[any](), NewSliceMemory(10))
+		g.AddNode("triage", reply(tc.content))
+		g.AddNode("billing", reply("billing"))
+		g.AddNode("support", reply("support"))
+		g.AddRelation(Entrypoint, "triage")
+		g.AddRelation("triage", "support")
+		g.AddConditionalEdge("triage", route, "billing", "support")
+
+		response, err := g.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(response.Results); got != tc.want {
+			t.Errorf("%q: results = %s, want %s", tc.content, got, tc.want)
+		}
+	}
+}
+
+func TestConditionalEdgeReadsTheBag(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("triage", &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		bag.Set("done", true)
+		return AgentResponse{}
+	}})
+	g.AddNode("again", reply("again"))
+	g.AddRelation(Entrypoint, "triage")
+	g.AddConditionalEdge("triage", func(bag *Bag[any], response AgentResponse) string {
+		if bag.Get("done") == true {
+			return Exitpoint
+		}
+		return "again"
+	}, "again", Exitpoint)
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.Results); got != "triage" {
+		t.Fatalf("results = %s, want the graph to end after triage", got)
+	}
+}
+
+func TestConditionalEdgeUndeclaredTarget(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("triage", reply("hola"))
+	g.AddNode("billing", reply("billing"))
+	g.AddNode("other", reply("other"))
+	g.AddRelation(Entrypoint, "triage")
+	g.AddConditionalEdge("triage", func(bag *Bag[any], response AgentResponse) string {
+		return "other"
+	}, "billing")
+
+	if _, err := g.Run(context.Background()); !errors.Is(err, ErrUndeclaredTarget) {
+		t.Fatalf("err = %v, want ErrUndeclaredTarget", err)
+	}
+
+	// Validate tambien avisa de un destino que no existe
+	g.AddConditionalEdge("triage", func(bag *Bag[any], response AgentResponse) string { return "" }, "missing")
+	if !hasDiagnostic(g.Validate(), SeverityError, DiagUnknownAgent, "triage") {
+		t.Fatalf("diagnostics = %v", g.Validate())
+	}
+}
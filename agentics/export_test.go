@@ -0,0 +1,17 @@
+package agentics
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// StartFakeRedis deja el servidor RESP de prueba a los tests de agentics_test.
+func StartFakeRedis(t *testing.T) string {
+	addr, _ := startFakeRedis(t)
+	return addr
+}
+
+// OpenTestDB abre una base SQLite temporal, o devuelve nil sin cgo.
+func OpenTestDB(t *testing.T) *sql.DB {
+	return openTestDB(t)
+}
@@ -0,0 +1,129 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type MergeFunc func(bag *Bag[any], results []AgentResult) error
+
+// Join junta las ramas de un fan-out. Si Quorum es 0 espera a todas, si no
+// sigue en cuanto Quorum ramas terminaron bien y cancela el resto.
+// Sin Merge, el contenido de cada rama se guarda en el Bag con su nombre.
+type Join struct {
+	Name     string
+	Branches []string
+	Quorum   int
+	Merge    MergeFunc
+}
+
+// AddFanOut hace que, al terminar from, las ramas corran en paralelo con una
+// copia propia de la memoria. El grafo sigue por las relaciones de join.Name.
+func (g *Graph) AddFanOut(from string, join Join) {
+	if g.FanOuts == nil {
+		g.FanOuts = make(map[string]Join)
+	}
+	g.FanOuts[from] = join
+}
+
+func (g *Graph) runFanOut(ctx context.Context, join Join, bag *Bag[any], mem Memory, emit EventHandler) ([]AgentResult, error) {
+	for _, name := range join.Branches {
+		if err := g.checkTarget(join.Name, name); err != nil {
+			return nil, err
+		}
+	}
+
+	quorum := join.Quorum
+	if quorum <= 0 || quorum > len(join.Branches) {
+		quorum = len(join.Branches)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type branchResult struct {
+		response AgentResponse
+		results  []AgentResult
+	}
+	branches := make([]branchResult, len(join.Branches))
+	done := make(chan int, len(join.Branches))
+
+	var wg sync.WaitGroup
+	for i, name := range join.Branches {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			response, results := g.runNode(ctx, name, bag, forkMemory(mem), emit)
+			branches[i] = branchResult{response, results}
+			done <- i
+		}(i, name)
+	}
+
+	succeeded := 0
+	for range join.Branches {
+		i := <-done
+		if branches[i].response.Error == nil {
+			succeeded++
+		}
+		if succeeded >= quorum {
+			cancel()
+			break
+		}
+	}
+	wg.Wait()
+
+	results := []AgentResult{}
+	merged := []AgentResult{}
+	var interrupted *InterruptError
+	for i, branch := range branches {
+		results = append(results, branch.results...)
+		if len(branch.results) > 0 && branch.response.Error == nil {
+			merged = append(merged, branch.results[len(branch.results)-1])
+		}
+		if interrupted == nil && errors.As(branch.response.Error, &interrupted) {
+			interrupted.Interrupt.Agent = join.Branches[i]
+		}
+	}
+
+	// Una rama que pide intervencion humana frena el join hasta reanudar, salvo
+	// que el quorum ya se haya alcanzado sin ella
+	if succeeded < quorum && interrupted != nil {
+		return results, fmt.Errorf("join %s: %w", join.Name, interrupted)
+	}
+	if succeeded < quorum {
+		return results, fmt.Errorf("join %s: only %d of %d branches succeeded", join.Name, succeeded, quorum)
+	}
+
+	merge := join.Merge
+	if merge == nil {
+		merge = mergeByName
+	}
+	if err := merge(bag, merged); err != nil {
+		return results, fmt.Errorf("join %s: %w", join.Name, err)
+	}
+
+	return results, nil
+}
+
+func mergeByName(bag *Bag[any], results []AgentResult) error {
+	for _, result := range results {
+		bag.Set(result.Name, result.Content)
+	}
+	return nil
+}
+
+func forkMemory(mem Memory) Memory {
+	snapshot := mem.Snapshot()
+
+	// Las memorias persistentes no se copian al backend, la rama trabaja
+	// sobre una copia en el proceso
+	fork, err := newMemoryLike(mem)
+	if err != nil {
+		fork = NewSliceMemory(len(snapshot) + 10)
+	}
+	fork.Restore(snapshot)
+
+	return fork
+}
@@ -0,0 +1,52 @@
+package agentics
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGraphFanOut(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("plan", reply("plan"))
+	g.AddNode("flights", reply("vuelo"))
+	g.AddNode("hotels", reply("hotel"))
+	g.AddNode("summary", &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		return AgentResponse{Content: bag.Get("flights").(string) + " y " + bag.Get("hotels").(string)}
+	}})
+	g.AddRelation(Entrypoint, "plan")
+	g.AddFanOut("plan", Join{Name: "join", Branches: []string{"flights", "hotels"}})
+	g.AddRelation("join", "summary")
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := response.Results[len(response.Results)-1]
+	if last.Name != "summary" || last.Content != "vuelo y hotel" {
+		t.Fatalf("results = %+v", response.Results)
+	}
+
+	// Cada rama escribe en su copia de la memoria
+	if got := strings.Join(response.Mem.ToArrayString(), ","); got != "plan" {
+		t.Fatalf("memory = %s", got)
+	}
+}
+
+func TestGraphFanOutQuorum(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("plan", reply("plan"))
+	g.AddNode("fast", reply("fast"))
+	g.AddNode("broken", failing(context.DeadlineExceeded))
+	g.AddRelation(Entrypoint, "plan")
+	g.AddFanOut("plan", Join{Name: "join", Branches: []string{"fast", "broken"}, Quorum: 1})
+
+	if _, err := g.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	g.AddFanOut("plan", Join{Name: "join", Branches: []string{"fast", "broken"}})
+	if _, err := g.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "only 1 of 2 branches succeeded") {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/subosito/gotenv"
 )
@@ -14,9 +15,28 @@ type JsonGraph struct {
 	State    []State                `json:"state"`
 	Nodes    []Node                 `json:"nodes"`
 	Edges    []Edge                 `json:"edges"`
+	Cyclic   bool                   `json:"cyclic,omitempty"`
+	MaxSteps int                    `json:"max_steps,omitempty"`
+	Memory   *JsonMemory            `json:"memory,omitempty"`
 	Metadata map[string]interface{} `json:"metadata"`
 }
 
+// JsonMemory elige el backend de memoria: "slice" (por defecto), "file",
+// "redis", "tokens" o "summary". SQL necesita un *sql.DB y se arma desde Go
+// con NewSQLMemory.
+type JsonMemory struct {
+	Type    string `json:"type"`
+	Max     int    `json:"max,omitempty"`
+	Dir     string `json:"dir,omitempty"`
+	Addr    string `json:"addr,omitempty"`
+	Session string `json:"session,omitempty"`
+
+	// Para "tokens" y "summary": presupuesto explicito o el del modelo
+	MaxTokens int       `json:"max_tokens,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	Provider  ModelType `json:"provider,omitempty"` // cliente que resume, por defecto openai
+}
+
 type State struct {
 	Name string `json:"name"`
 	Type string `json:"type"`
@@ -29,6 +49,17 @@ type Node struct {
 	Branches  []string   `json:"branches,omitempty"` // solo existe en el orquestador
 	Functions []Function `json:"functions,omitempty"`
 	Tools     []JsonTool `json:"tools,omitempty"`
+
+	InputGuardrails  []string     `json:"input_guardrails,omitempty"`
+	OutputGuardrails []string     `json:"output_guardrails,omitempty"`
+	OnError          *ErrorPolicy `json:"on_error,omitempty"`
+	MaxVisits        int          `json:"max_visits,omitempty"`
+
+	// Nodos de tipo "graph": otro archivo JSON, relativo a este
+	Graph          string            `json:"graph,omitempty"`
+	Inputs         map[string]string `json:"inputs,omitempty"`
+	Outputs        map[string]string `json:"outputs,omitempty"`
+	IsolatedMemory bool              `json:"isolated_memory,omitempty"`
 }
 
 type JsonTool struct {
@@ -47,19 +78,45 @@ type Edge struct {
 	Target string `json:"target"`
 }
 
+// FromJson es LoadJson para programas: si el grafo no se puede cargar corta
+// el proceso. Desde una libreria o un servidor usar LoadJson.
 func FromJson(file *os.File) *Graph {
+	graph, err := LoadJson(file)
+	if err != nil {
+		log.Fatalf("error al cargar el grafo: %v", err)
+	}
+	return graph
+}
+
+// LoadJson arma el grafo definido en file. Devuelve error si el JSON es
+// invalido o si no se puede abrir la memoria, por ejemplo un Redis caido o un
+// directorio sin permisos.
+func LoadJson(file *os.File) (*Graph, error) {
 	err := gotenv.Load()
 	if err != nil {
 		fmt.Println("Warning: Error loading .env file:", err)
 	}
 
+	return fromJson(file, map[string]bool{})
+}
+
+// loading tiene los archivos que se estan cargando, para cortar referencias
+// circulares entre sub-grafos.
+func fromJson(file *os.File, loading map[string]bool) (*Graph, error) {
+	path, _ := filepath.Abs(file.Name())
+	loading[path] = true
+	defer delete(loading, path)
+
 	var jsonGraph JsonGraph
 
 	if err := json.NewDecoder(file).Decode(&jsonGraph); err != nil {
-		log.Fatalf("error al decodificar: %v", err)
+		return nil, err
 	}
 
-	mem := NewSliceMemory(10)
+	mem, err := memoryFromJson(jsonGraph.Memory)
+	if err != nil {
+		return nil, err
+	}
 	bag := NewBag[any]()
 
 	for _, s := range jsonGraph.State {
@@ -75,6 +132,14 @@ func FromJson(file *os.File) *Graph {
 
 	graph := NewGraph(bag, mem)
 	for _, node := range jsonGraph.Nodes {
+		if node.Type == "graph" {
+			graph.AddNode(node.Name, subGraphFromJson(filepath.Dir(path), node, loading))
+			if node.OnError != nil {
+				graph.SetErrorPolicy(node.Name, *node.OnError)
+			}
+			continue
+		}
+
 		var opts []AgentOption
 
 		for _, fn := range node.Functions {
@@ -91,8 +156,12 @@ func FromJson(file *os.File) *Graph {
 			for _, tool := range node.Tools {
 				funcTool, ok := getTool(tool.Name)
 				if !ok {
-					fmt.Println("error tools")
-					return nil
+					opts = append(opts, withDiagnostic(Diagnostic{
+						Severity: SeverityError,
+						Code:     DiagUnregisteredTool,
+						Message:  fmt.Sprintf("tool %q is not registered", tool.Name),
+					}))
+					continue
 				}
 				t := NewTool(
 					tool.Name,
@@ -105,19 +174,140 @@ func FromJson(file *os.File) *Graph {
 			opts = append(opts, WithTools(tools))
 		}
 
+		if len(node.InputGuardrails) > 0 {
+			opts = append(opts, WithInputGuardrails(node.InputGuardrails))
+		}
+
+		if len(node.OutputGuardrails) > 0 {
+			opts = append(opts, WithOutputGuardrails(node.OutputGuardrails))
+		}
+
 		if node.Type == "orchestrator" {
 			opts = append(opts, WithBranchs(node.Branches))
 		}
 
 		a := NewAgent(node.Name, node.Prompt, opts...)
 		graph.AddAgent(a)
+
+		if node.OnError != nil {
+			graph.SetErrorPolicy(node.Name, *node.OnError)
+		}
+
+		if node.MaxVisits > 0 {
+			graph.SetMaxVisits(node.Name, node.MaxVisits)
+		}
 	}
 
 	graph.SetEntrypoint(jsonGraph.Entry)
+	if jsonGraph.Cyclic {
+		graph.SetCyclic(jsonGraph.MaxSteps)
+	}
 
 	for _, edge := range jsonGraph.Edges {
 		graph.AddRelation(edge.Source, edge.Target)
 	}
 
-	return graph
+	return graph, nil
+}
+
+func subGraphFromJson(dir string, node Node, loading map[string]bool) *SubGraph {
+	options := []SubGraphOption{WithInputs(node.Inputs), WithOutputs(node.Outputs)}
+	if node.IsolatedMemory {
+		options = append(options, WithIsolatedMemory())
+	}
+	sub := NewSubGraph(node.Name, nil, options...)
+
+	path := node.Graph
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil && loading[abs] {
+		sub.diagnostics = append(sub.diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Code:     DiagInvalidSubGraph,
+			Message:  fmt.Sprintf("sub-graph %q refers back to a graph that is still loading", node.Graph),
+		})
+		return sub
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		sub.diagnostics = append(sub.diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Code:     DiagInvalidSubGraph,
+			Message:  fmt.Sprintf("cannot open sub-graph: %v", err),
+		})
+		return sub
+	}
+	defer file.Close()
+
+	graph, err := fromJson(file, loading)
+	if err != nil {
+		sub.diagnostics = append(sub.diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Code:     DiagInvalidSubGraph,
+			Message:  fmt.Sprintf("cannot load sub-graph %s: %v", node.Graph, err),
+		})
+		return sub
+	}
+	sub.Graph = graph
+	return sub
+}
+
+// memoryFromJson falla con una configuracion invalida o un backend que no
+// responde, en lugar de seguir con otra memoria.
+func memoryFromJson(config *JsonMemory) (Memory, error) {
+	if config == nil {
+		return NewSliceMemory(10), nil
+	}
+
+	max := config.Max
+	if max <= 0 {
+		max = 10
+	}
+	session := config.Session
+	if session == "" {
+		session = "default"
+	}
+
+	switch config.Type {
+	case "", "slice":
+		return NewSliceMemory(max), nil
+	case "file":
+		mem, err := NewFileMemory(config.Dir, session, max)
+		if err != nil {
+			return nil, fmt.Errorf("file memory: %w", err)
+		}
+		return mem, nil
+	case "redis":
+		mem, err := NewRedisMemory(config.Addr, session, max)
+		if err != nil {
+			return nil, fmt.Errorf("redis memory: %w", err)
+		}
+		return mem, nil
+	case "tokens":
+		return NewTokenMemory(tokenBudget(config)), nil
+	case "summary":
+		provider := config.Provider
+		if provider == "" {
+			provider = OpenAI
+		}
+		client := NewModelClient(provider)
+		if client.provider == nil {
+			return nil, fmt.Errorf("summary memory: unknown provider %q", provider)
+		}
+		if config.Model != "" {
+			client.provider.SetModel(config.Model)
+		}
+		return NewSummaryMemory(client, tokenBudget(config)), nil
+	}
+
+	return nil, fmt.Errorf("unknown memory type %q", config.Type)
+}
+
+func tokenBudget(config *JsonMemory) int {
+	if config.MaxTokens > 0 {
+		return config.MaxTokens
+	}
+	return ContextWindow(config.Model) / 2
 }
@@ -0,0 +1,94 @@
+package agentics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryFromJson(t *testing.T) {
+	addr, _ := startFakeRedis(t)
+	dir := t.TempDir()
+
+	valid := map[string]*JsonMemory{
+		"default": nil,
+		"slice":   {Type: "slice", Max: 5},
+		"tokens":  {Type: "tokens", MaxTokens: 100},
+		"file":    {Type: "file", Dir: dir, Session: "ana"},
+		"redis":   {Type: "redis", Addr: addr},
+	}
+	for name, config := range valid {
+		if _, err := memoryFromJson(config); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	invalid := map[string]*JsonMemory{
+		"unknown type":     {Type: "mongo"},
+		"unknown provider": {Type: "summary", Provider: "cohere"},
+		"redis down":       {Type: "redis", Addr: "127.0.0.1:1"},
+	}
+	for name, config := range invalid {
+		if mem, err := memoryFromJson(config); err == nil {
+			t.Errorf("%s: got %T, want an error", name, mem)
+		}
+	}
+}
+
+func writeJson(t *testing.T, dir string, name string, content string) *os.File {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func TestLoadJson(t *testing.T) {
+	dir := t.TempDir()
+
+	graph, err := LoadJson(writeJson(t, dir, "ok.json", `{"entry": "a", "nodes": [{"name": "a", "prompt": "hola"}]}`))
+	if err != nil || graph.Entrypoint != "a" {
+		t.Fatalf("graph = %+v, err = %v", graph, err)
+	}
+
+	// Un backend que no abre es un error, no un os.Exit
+	for name, content := range map[string]string{
+		"redis.json":   `{"entry": "a", "memory": {"type": "redis", "addr": "127.0.0.1:1"}, "nodes": [{"name": "a"}]}`,
+		"invalid.json": `{"entry": `,
+	} {
+		if _, err := LoadJson(writeJson(t, dir, name, content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSubGraphFromJsonErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) *os.File {
+		return writeJson(t, dir, name, content)
+	}
+
+	write("child.json", `{"entry": "a", "memory": {"type": "mongo"}, "nodes": [{"name": "a"}]}`)
+	write("self.json", `{"entry": "again", "nodes": [{"name": "again", "type": "graph", "graph": "self.json"}]}`)
+	parent := write("parent.json", `{"entry": "child", "nodes": [
+		{"name": "child", "type": "graph", "graph": "child.json"},
+		{"name": "self", "type": "graph", "graph": "self.json"},
+		{"name": "missing", "type": "graph", "graph": "missing.json"}
+	], "edges": [{"source": "child", "target": "self"}, {"source": "self", "target": "missing"}]}`)
+
+	g, err := fromJson(parent, map[string]bool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagnostics := g.Validate()
+	for _, node := range []string{"child", "self/again", "missing"} {
+		if !hasDiagnostic(diagnostics, SeverityError, DiagInvalidSubGraph, node) {
+			t.Errorf("missing invalid_subgraph on %s in %v", node, diagnostics)
+		}
+	}
+}
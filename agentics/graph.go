@@ -2,6 +2,10 @@ package agentics
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 )
 
 const (
@@ -9,17 +13,71 @@ const (
 	Exitpoint  = "END"
 )
 
+var (
+	ErrMaxStepsExceeded = errors.New("max steps exceeded")
+	ErrUnknownAgent     = errors.New("unknown agent")
+	ErrNoEntrypoint     = errors.New("graph has no entrypoint")
+	ErrUndeclaredTarget = errors.New("undeclared conditional target")
+)
+
+const defaultMaxSteps = 25
+
 type Graph struct {
-	Entrypoint string
-	Agents     map[string]AgentInterface
-	Relations  [][]string
-	Bag        *Bag[any]
-	Mem        Memory
+	Entrypoint    string
+	Agents        map[string]AgentInterface
+	Relations     [][]string
+	ErrorPolicies map[string]ErrorPolicy
+	FanOuts       map[string]Join
+	Conditions    map[string]ConditionalEdge
+	Checkpointer  Checkpointer
+	Cyclic        bool
+	MaxSteps      int
+	MaxVisits     map[string]int
+	Bag           *Bag[any]
+	Mem           Memory
 }
 
 type GraphResponse struct {
-	Bag *Bag[any]
-	Mem Memory
+	RunID     string
+	Interrupt *Interrupt // seteado cuando la corrida quedo esperando una respuesta humana
+	Bag       *Bag[any]
+	Mem       Memory
+	Results   []AgentResult
+}
+
+type AgentResult struct {
+	Name      string
+	Content   string
+	Output    interface{}
+	Error     error
+	Duration  time.Duration
+	NextAgent string
+}
+
+type ErrorAction string
+
+const (
+	ErrorFailFast ErrorAction = "fail_fast"
+	ErrorContinue ErrorAction = "continue"
+	ErrorRetry    ErrorAction = "retry"
+	ErrorFallback ErrorAction = "fallback"
+)
+
+// ErrorPolicy define que hace el grafo cuando un agente devuelve error.
+// Con ErrorRetry, si se agotan los reintentos y hay Fallback se sigue por ahi.
+type ErrorPolicy struct {
+	Action   ErrorAction `json:"action"`
+	Retries  int         `json:"retries,omitempty"`
+	Fallback string      `json:"fallback,omitempty"`
+}
+
+// RouteFunc decide en Go a donde sigue el grafo despues de que corre un
+// agente. Devolver "" sigue por las relaciones normales.
+type RouteFunc func(bag *Bag[any], response AgentResponse) string
+
+type ConditionalEdge struct {
+	Route   RouteFunc
+	Targets []string
 }
 
 func (g *Graph) AddAgent(agent *Agent) {
@@ -29,6 +87,14 @@ func (g *Graph) AddAgent(agent *Agent) {
 	g.Agents[agent.Name] = agent
 }
 
+// AddNode agrega cualquier AgentInterface como nodo, por ejemplo un HumanNode.
+func (g *Graph) AddNode(name string, node AgentInterface) {
+	if g.Agents == nil {
+		g.Agents = make(map[string]AgentInterface)
+	}
+	g.Agents[name] = node
+}
+
 func (g *Graph) AddRelation(from, to string) {
 	if g.Relations == nil {
 		g.Relations = [][]string{}
@@ -36,6 +102,19 @@ func (g *Graph) AddRelation(from, to string) {
 	g.Relations = append(g.Relations, []string{from, to})
 }
 
+// AddConditionalEdge rutea la salida de from con route. targets son los
+// destinos posibles: se usan para validar el grafo y route no puede devolver
+// otro.
+func (g *Graph) AddConditionalEdge(from string, route RouteFunc, targets ...string) {
+	if g.Conditions == nil {
+		g.Conditions = make(map[string]ConditionalEdge)
+	}
+	g.Conditions[from] = ConditionalEdge{
+		Route:   route,
+		Targets: targets,
+	}
+}
+
 func (g *Graph) SetEntrypoint(agent string) {
 	g.Entrypoint = agent
 }
@@ -46,38 +125,357 @@ func NewGraph(bag *Bag[any], mem Memory) *Graph {
 		Mem: mem,
 	}
 }
-func (g *Graph) Run(ctx context.Context) *GraphResponse {
-	currentAgent := g.Entrypoint
-	visited := make(map[string]bool)
-	queue := []string{currentAgent}
+func (g *Graph) SetErrorPolicy(agent string, policy ErrorPolicy) {
+	if g.ErrorPolicies == nil {
+		g.ErrorPolicies = make(map[string]ErrorPolicy)
+	}
+	g.ErrorPolicies[agent] = policy
+}
 
-	for len(queue) > 0 {
-		currentAgent = queue[0]
-		queue = queue[1:]
+// SetCyclic permite que un agente corra mas de una vez por ejecucion, por
+// ejemplo writer -> critic -> writer. maxSteps limita la cantidad total de pasos.
+// Un nodo que ya esta esperando en la cola no se vuelve a encolar, pero no se
+// espera a todas sus entradas: si las ramas de un diamante tienen distinto
+// largo el nodo final corre una vez por rama. Para juntar ramas usar AddFanOut.
+func (g *Graph) SetCyclic(maxSteps int) {
+	g.Cyclic = true
+	g.MaxSteps = maxSteps
+}
 
-		if visited[currentAgent] {
+func (g *Graph) SetMaxVisits(agent string, maxVisits int) {
+	if g.MaxVisits == nil {
+		g.MaxVisits = make(map[string]int)
+	}
+	g.MaxVisits[agent] = maxVisits
+}
+
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	runID string
+}
+
+// WithRunID usa id como id de la corrida en lugar de generar uno, asi quien
+// llama lo conoce antes de que termine y puede reanudarla despues de un crash.
+// Con un Checkpointer, un id que ya existe pisa el checkpoint anterior.
+func WithRunID(id string) RunOption {
+	return func(c *runConfig) {
+		c.runID = id
+	}
+}
+
+func newRunConfig(opts []RunOption) runConfig {
+	config := runConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.runID == "" {
+		config.runID = newSessionID()
+	}
+	return config
+}
+
+func (g *Graph) Run(ctx context.Context, opts ...RunOption) (*GraphResponse, error) {
+	config := newRunConfig(opts)
+	return g.start(ctx, config.runID, g.Bag, g.Mem, nil)
+}
+
+func (g *Graph) RunStream(ctx context.Context, handler EventHandler, opts ...RunOption) (*GraphResponse, error) {
+	config := newRunConfig(opts)
+	return g.start(ctx, config.runID, g.Bag, g.Mem, syncHandler(handler))
+}
+
+func (g *Graph) start(ctx context.Context, runID string, bag *Bag[any], mem Memory, emit EventHandler) (*GraphResponse, error) {
+	queue, err := g.startNodes()
+	if err != nil {
+		return &GraphResponse{RunID: runID, Bag: bag, Mem: mem}, err
+	}
+
+	cp := &Checkpoint{
+		RunID:  runID,
+		Queue:  queue,
+		Visits: make(map[string]int),
+	}
+	return g.run(ctx, cp, bag, mem, emit, -1)
+}
+
+// run ejecuta desde el estado de cp (cola, visitas y pasos) y lo va
+// actualizando. Si hay Checkpointer se guarda cada vez que avanza un paso.
+func (g *Graph) run(ctx context.Context, cp *Checkpoint, bag *Bag[any], mem Memory, emit EventHandler, saved int) (*GraphResponse, error) {
+	result := &GraphResponse{
+		RunID: cp.RunID,
+		Bag:   bag,
+		Mem:   mem,
+	}
+	ctx = context.WithValue(ctx, runningKey{}, &running{graph: g, parent: runningGraphs(ctx)})
+
+	var currentAgent string
+	visits := cp.Visits
+	maxSteps := g.MaxSteps
+	if g.Cyclic && maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
+
+	for len(cp.Queue) > 0 {
+		if cp.Step > saved {
+			if err := g.checkpoint(ctx, cp, bag, mem); err != nil {
+				return result, err
+			}
+			saved = cp.Step
+		}
+
+		currentAgent = cp.Queue[0]
+		cp.Queue = cp.Queue[1:]
+
+		// Un fan-out interrumpido queda en la cola con el nombre del join y al
+		// reanudar vuelven a correr sus ramas, no el agente que lo abrio
+		if join, ok := g.joinNamed(currentAgent); ok {
+			if err := g.fanOut(ctx, cp, join, bag, mem, emit, result); err != nil {
+				return result, err
+			}
+			if err := g.follow(ctx, cp, join.Name, AgentResponse{}, bag, result); err != nil {
+				return result, err
+			}
+			continue
+		}
+
+		// END en una relacion termina ese camino
+		if currentAgent == Exitpoint {
+			continue
+		}
+		if !g.Cyclic && visits[currentAgent] > 0 {
 			continue
 		}
+		if maxVisits, ok := g.MaxVisits[currentAgent]; ok && visits[currentAgent] >= maxVisits {
+			fmt.Printf("Skipping agent %s: max visits reached\n", currentAgent)
+			continue
+		}
+		if maxSteps > 0 && cp.Step >= maxSteps {
+			return result, fmt.Errorf("%w: %d", ErrMaxStepsExceeded, maxSteps)
+		}
 
-		visited[currentAgent] = true
-		agent := g.Agents[currentAgent]
-		response := agent.Run(ctx, g.Bag, g.Mem)
+		visits[currentAgent]++
+		cp.Step++
+		policy := g.ErrorPolicies[currentAgent]
+		response, results := g.runNode(ctx, currentAgent, bag, mem, emit)
+		result.Results = append(result.Results, results...)
 
-		if response.NextAgent != "" {
-			queue = append([]string{response.NextAgent}, queue...)
-		} else {
-			for _, relation := range g.Relations {
-				if relation[0] == currentAgent && !visited[relation[1]] {
-					queue = append(queue, relation[1])
+		// La corrida queda en pausa antes de este agente, que vuelve a correr al reanudar
+		if errors.Is(response.Error, ErrInterrupted) {
+			visits[currentAgent]--
+			cp.Step--
+			return result, g.pause(ctx, cp, currentAgent, currentAgent, response.Error, bag, mem, result)
+		}
+
+		// Un guardrail de entrada puede cortar el grafo con una respuesta fija o un error
+		if errors.Is(response.Error, ErrGuardrailTripped) {
+			return result, fmt.Errorf("agent %s: %w", currentAgent, response.Error)
+		}
+		if response.NextAgent == Exitpoint {
+			cp.Queue = nil
+			break
+		}
+
+		if response.Error != nil {
+			switch {
+			case policy.Action == ErrorContinue:
+			case policy.Fallback != "" && (policy.Action == ErrorFallback || policy.Action == ErrorRetry):
+				if err := g.checkTarget(currentAgent, policy.Fallback); err != nil {
+					return result, err
 				}
+				cp.Queue = append([]string{policy.Fallback}, cp.Queue...)
+				continue
+			default:
+				return result, fmt.Errorf("agent %s: %w", currentAgent, response.Error)
+			}
+		}
+
+		from := currentAgent
+		if join, ok := g.FanOuts[currentAgent]; ok && response.NextAgent == "" {
+			if err := g.fanOut(ctx, cp, join, bag, mem, emit, result); err != nil {
+				return result, err
+			}
+			from = join.Name
+		}
+
+		if err := g.follow(ctx, cp, from, response, bag, result); err != nil {
+			return result, err
+		}
+	}
+
+	cp.Done = true
+	if err := g.checkpoint(ctx, cp, bag, mem); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// follow encola lo que sigue despues de from: el destino que eligio el
+// agente o un conditional edge, o si no las relaciones que salen de from.
+func (g *Graph) follow(ctx context.Context, cp *Checkpoint, from string, response AgentResponse, bag *Bag[any], result *GraphResponse) error {
+	next := response.NextAgent
+	if edge, ok := g.Conditions[from]; ok && next == "" {
+		next = edge.Route(bag, response)
+		if next != "" && !slices.Contains(edge.Targets, next) {
+			return fmt.Errorf("%w: %s routes to %q", ErrUndeclaredTarget, from, next)
+		}
+	}
+
+	if next != "" {
+		if err := g.checkTarget(from, next); err != nil {
+			return err
+		}
+		cp.Queue = append([]string{next}, cp.Queue...)
+		return nil
+	}
+
+	for _, relation := range g.Relations {
+		if relation[0] != from || (!g.Cyclic && cp.Visits[relation[1]] > 0) {
+			continue
+		}
+		// Un nodo que ya esta en la cola no se encola de nuevo: en a -> b,
+		// a -> c, b -> d, c -> d, d corre una vez. Si una rama es mas larga d
+		// ya corrio cuando llega y vuelve a correr
+		if g.Cyclic && slices.Contains(cp.Queue, relation[1]) {
+			continue
+		}
+		if err := g.checkTarget(from, relation[1]); err != nil {
+			return err
+		}
+		cp.Queue = append(cp.Queue, relation[1])
+	}
+	return nil
+}
+
+// fanOut corre las ramas de join. Si una rama pide intervencion humana la
+// corrida queda en pausa con el join al frente de la cola.
+func (g *Graph) fanOut(ctx context.Context, cp *Checkpoint, join Join, bag *Bag[any], mem Memory, emit EventHandler, result *GraphResponse) error {
+	results, err := g.runFanOut(ctx, join, bag, mem, emit)
+	result.Results = append(result.Results, results...)
+	if errors.Is(err, ErrInterrupted) {
+		var interrupted *InterruptError
+		agent := join.Name
+		if errors.As(err, &interrupted) && interrupted.Interrupt.Agent != "" {
+			agent = interrupted.Interrupt.Agent
+		}
+		return g.pause(ctx, cp, join.Name, agent, err, bag, mem, result)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, branch := range join.Branches {
+		cp.Visits[branch]++
+	}
+	cp.Step += len(join.Branches)
+	return nil
+}
+
+// pause deja node al frente de la cola y guarda el interrupt, para que
+// ResumeWithAnswer vuelva a correrlo.
+func (g *Graph) pause(ctx context.Context, cp *Checkpoint, node string, agent string, err error, bag *Bag[any], mem Memory, result *GraphResponse) error {
+	cp.Queue = append([]string{node}, cp.Queue...)
+
+	var interrupted *InterruptError
+	if errors.As(err, &interrupted) {
+		interrupt := interrupted.Interrupt
+		interrupt.RunID = cp.RunID
+		interrupt.Agent = agent
+		cp.Interrupt = &interrupt
+		result.Interrupt = &interrupt
+	}
+	if err := g.checkpoint(ctx, cp, bag, mem); err != nil {
+		return err
+	}
+	return fmt.Errorf("agent %s: %w", agent, err)
+}
+
+func (g *Graph) joinNamed(name string) (Join, bool) {
+	for _, join := range g.FanOuts {
+		if join.Name == name {
+			return join, true
+		}
+	}
+	return Join{}, false
+}
+
+// startNodes devuelve el entrypoint, o si no se seteo, los destinos de las
+// relaciones que salen de START.
+func (g *Graph) startNodes() ([]string, error) {
+	if g.Entrypoint != "" && g.Entrypoint != Entrypoint {
+		if err := g.checkTarget(Entrypoint, g.Entrypoint); err != nil {
+			return nil, err
+		}
+		return []string{g.Entrypoint}, nil
+	}
+
+	start := []string{}
+	for _, relation := range g.Relations {
+		if relation[0] == Entrypoint {
+			if err := g.checkTarget(Entrypoint, relation[1]); err != nil {
+				return nil, err
 			}
+			start = append(start, relation[1])
 		}
+	}
+	if len(start) == 0 {
+		return nil, ErrNoEntrypoint
+	}
+
+	return start, nil
+}
+
+func (g *Graph) checkTarget(from, to string) error {
+	if to == Exitpoint {
+		return nil
+	}
+	if _, ok := g.Agents[to]; !ok {
+		return fmt.Errorf("%w: %s routes to %q", ErrUnknownAgent, from, to)
+	}
+	return nil
+}
 
-		g.Mem.Add("assistant", response.Content)
+// runNode corre un agente aplicando los reintentos de su ErrorPolicy y
+// devuelve un AgentResult por cada intento.
+func (g *Graph) runNode(ctx context.Context, name string, bag *Bag[any], mem Memory, emit EventHandler) (AgentResponse, []AgentResult) {
+	agent := g.Agents[name]
+	policy := g.ErrorPolicies[name]
+	results := []AgentResult{}
+
+	var response AgentResponse
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		response = g.runAgent(ctx, name, agent, bag, mem, emit)
+		results = append(results, AgentResult{
+			Name:      name,
+			Content:   response.Content,
+			Output:    response.Output,
+			Error:     response.Error,
+			Duration:  time.Since(start),
+			NextAgent: response.NextAgent,
+		})
+
+		if response.Error == nil || policy.Action != ErrorRetry || attempt >= policy.Retries || errors.Is(response.Error, ErrInterrupted) {
+			break
+		}
+		fmt.Printf("Retrying agent %s after error: %v\n", name, response.Error)
 	}
 
-	return &GraphResponse{
-		Bag: g.Bag,
-		Mem: g.Mem,
+	return response, results
+}
+
+func (g *Graph) runAgent(ctx context.Context, name string, agent AgentInterface, bag *Bag[any], mem Memory, emit EventHandler) AgentResponse {
+	if emit == nil {
+		return agent.Run(ctx, bag, mem)
+	}
+	if streaming, ok := agent.(StreamingAgent); ok {
+		return streaming.RunStream(ctx, bag, mem, emit)
 	}
+
+	emit(Event{Type: EventAgentStarted, Agent: name})
+	response := agent.Run(ctx, bag, mem)
+	emit(Event{Type: EventAgentFinished, Agent: name, Response: &response})
+
+	return response
 }
@@ -0,0 +1,238 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeNode es un nodo del grafo sin modelo: corre run y cuenta las visitas.
+type fakeNode struct {
+	mu     sync.Mutex
+	visits int
+	run    func(bag *Bag[any], mem Memory) AgentResponse
+}
+
+func (n *fakeNode) Run(ctx context.Context, bag *Bag[any], mem Memory) AgentResponse {
+	n.mu.Lock()
+	n.visits++
+	n.mu.Unlock()
+	if n.run == nil {
+		return AgentResponse{}
+	}
+	return n.run(bag, mem)
+}
+
+func (n *fakeNode) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.visits
+}
+
+func reply(content string) *fakeNode {
+	return &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		mem.Add("assistant", content)
+		return AgentResponse{Content: content}
+	}}
+}
+
+func failing(err error) *fakeNode {
+	return &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		return AgentResponse{Error: err}
+	}}
+}
+
+func names(results []AgentResult) string {
+	list := []string{}
+	for _, result := range results {
+		list = append(list, result.Name)
+	}
+	return strings.Join(list, ",")
+}
+
+func TestGraphRunResults(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("a", reply("uno"))
+	g.AddNode("b", reply("dos"))
+	g.AddRelation(Entrypoint, "a")
+	g.AddRelation("a", "b")
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names(response.Results) != "a,b" || response.Results[1].Content != "dos" {
+		t.Fatalf("results = %+v", response.Results)
+	}
+	if response.Mem.Len() != 2 {
+		t.Fatalf("memory = %v", response.Mem.ToArrayString())
+	}
+}
+
+func TestGraphRunErrors(t *testing.T) {
+	boom := errors.New("boom")
+
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("a", failing(boom))
+	g.AddNode("b", reply("dos"))
+	g.AddRelation(Entrypoint, "a")
+	g.AddRelation("a", "b")
+
+	response, err := g.Run(context.Background())
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "agent a") {
+		t.Fatalf("err = %v", err)
+	}
+	if names(response.Results) != "a" || response.Results[0].Error != boom {
+		t.Fatalf("results = %+v", response.Results)
+	}
+
+	// Con retry se reintenta y despues se sigue por el fallback
+	flaky := failing(boom)
+	g.AddNode("a", flaky)
+	g.AddNode("fallback", reply("perdon"))
+	g.SetErrorPolicy("a", ErrorPolicy{Action: ErrorRetry, Retries: 2, Fallback: "fallback"})
+	response, err = g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flaky.count() != 3 || names(response.Results) != "a,a,a,fallback" {
+		t.Fatalf("results = %s, visits = %d", names(response.Results), flaky.count())
+	}
+
+	// Con continue el error queda en los resultados y el grafo sigue
+	g.SetErrorPolicy("a", ErrorPolicy{Action: ErrorContinue})
+	response, err = g.Run(context.Background())
+	if err != nil || names(response.Results) != "a,b" {
+		t.Fatalf("results = %s, err = %v", names(response.Results), err)
+	}
+}
+
+func TestGraphCyclic(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	writer := &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		drafts, _ := bag.Get("drafts").(int)
+		bag.Set("drafts", drafts+1)
+		return AgentResponse{Content: "draft"}
+	}}
+	critic := &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		if bag.Get("drafts").(int) < 3 {
+			return AgentResponse{NextAgent: "writer"}
+		}
+		return AgentResponse{NextAgent: Exitpoint}
+	}}
+	g.AddNode("writer", writer)
+	g.AddNode("critic", critic)
+	g.AddRelation(Entrypoint, "writer")
+	g.AddRelation("writer", "critic")
+	g.SetCyclic(10)
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names(response.Results) != "writer,critic,writer,critic,writer,critic" {
+		t.Fatalf("results = %s", names(response.Results))
+	}
+
+	// Los limites cortan un ciclo que no termina
+	g.Bag = NewBag[any]()
+	g.AddNode("critic", &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		return AgentResponse{NextAgent: "writer"}
+	}})
+	if _, err := g.Run(context.Background()); !errors.Is(err, ErrMaxStepsExceeded) {
+		t.Fatalf("err = %v, want ErrMaxStepsExceeded", err)
+	}
+
+	g.Bag = NewBag[any]()
+	g.SetMaxVisits("writer", 2)
+	response, err = g.Run(context.Background())
+	if err != nil || names(response.Results) != "writer,critic,writer,critic" {
+		t.Fatalf("results = %s, err = %v", names(response.Results), err)
+	}
+}
+
+func TestGraphCyclicDiamond(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	join := reply("join")
+	g.AddNode("a", reply("a"))
+	g.AddNode("b", reply("b"))
+	g.AddNode("c", reply("c"))
+	g.AddNode("d", join)
+	g.AddRelation(Entrypoint, "a")
+	g.AddRelation("a", "b")
+	g.AddRelation("a", "c")
+	g.AddRelation("b", "d")
+	g.AddRelation("c", "d")
+	g.SetCyclic(10)
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if join.count() != 1 || names(response.Results) != "a,b,c,d" {
+		t.Fatalf("results = %s", names(response.Results))
+	}
+}
+
+func TestGraphCyclicUnevenDiamond(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	join := reply("join")
+	g.AddNode("a", reply("a"))
+	g.AddNode("b", reply("b"))
+	g.AddNode("c", reply("c"))
+	g.AddNode("x", reply("x"))
+	g.AddNode("d", join)
+	g.AddRelation(Entrypoint, "a")
+	g.AddRelation("a", "b")
+	g.AddRelation("a", "c")
+	g.AddRelation("b", "d")
+	g.AddRelation("c", "x")
+	g.AddRelation("x", "d")
+	g.SetCyclic(10)
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// El grafo no espera a las dos ramas: d corre despues de b y otra vez
+	// despues de x
+	if join.count() != 2 || names(response.Results) != "a,b,c,d,x,d" {
+		t.Fatalf("results = %s", names(response.Results))
+	}
+}
+
+func TestGraphStartAndEnd(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("a", reply("a"))
+	g.AddNode("b", &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		return AgentResponse{NextAgent: Exitpoint}
+	}})
+	g.AddNode("c", reply("c"))
+	g.AddNode("d", reply("d"))
+	g.AddRelation(Entrypoint, "a")
+	g.AddRelation(Entrypoint, "b")
+	g.AddRelation("a", Exitpoint)
+	g.AddRelation("b", "c")
+	g.AddRelation("d", "c")
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names(response.Results) != "a,b" {
+		t.Fatalf("results = %s", names(response.Results))
+	}
+
+	g.AddRelation("a", "missing")
+	if _, err := g.Run(context.Background()); !errors.Is(err, ErrUnknownAgent) {
+		t.Fatalf("err = %v, want ErrUnknownAgent", err)
+	}
+
+	empty := NewGraph(NewBag[any](), NewSliceMemory(10))
+	empty.AddNode("a", reply("a"))
+	if _, err := empty.Run(context.Background()); !errors.Is(err, ErrNoEntrypoint) {
+		t.Fatalf("err = %v, want ErrNoEntrypoint", err)
+	}
+}
@@ -0,0 +1,235 @@
+package agentics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type GuardrailAction string
+
+const (
+	GuardrailBlock   GuardrailAction = "block"
+	GuardrailRewrite GuardrailAction = "rewrite"
+	GuardrailRetry   GuardrailAction = "retry"
+	GuardrailRoute   GuardrailAction = "route"
+	GuardrailRespond GuardrailAction = "respond"
+)
+
+var ErrGuardrailTripped = errors.New("guardrail tripped")
+
+type Guardrail interface {
+	Check(ctx context.Context, bag *Bag[any], content string) GuardrailResult
+}
+
+type GuardrailResult struct {
+	Tripped bool
+	Reason  string
+	Action  GuardrailAction
+	Content string // contenido reescrito (GuardrailRewrite) o respuesta fija (GuardrailRespond)
+	Route   string // agente destino cuando Action es GuardrailRoute
+}
+
+type GuardrailFunc func(ctx context.Context, bag *Bag[any], content string) GuardrailResult
+
+func (f GuardrailFunc) Check(ctx context.Context, bag *Bag[any], content string) GuardrailResult {
+	return f(ctx, bag, content)
+}
+
+type GuardrailError struct {
+	Guardrail string
+	Reason    string
+}
+
+func (e *GuardrailError) Error() string {
+	return fmt.Sprintf("%v: %s: %s", ErrGuardrailTripped, e.Guardrail, e.Reason)
+}
+
+func (e *GuardrailError) Is(target error) bool {
+	return target == ErrGuardrailTripped
+}
+
+var guardrailRegistry = map[string]Guardrail{
+	"valid_json":       ValidJSON(),
+	"redact_pii":       RedactPII(),
+	"prompt_injection": PromptInjection(),
+}
+
+func RegisterGuardrail(name string, guardrail Guardrail) {
+	guardrailRegistry[name] = guardrail
+}
+
+func getGuardrail(name string) (Guardrail, bool) {
+	g, ok := guardrailRegistry[name]
+	return g, ok
+}
+
+// OnViolation cambia la accion que toma un guardrail cuando se dispara.
+// route solo se usa con GuardrailRoute.
+func OnViolation(guardrail Guardrail, action GuardrailAction, route ...string) Guardrail {
+	return GuardrailFunc(func(ctx context.Context, bag *Bag[any], content string) GuardrailResult {
+		result := guardrail.Check(ctx, bag, content)
+		if result.Tripped {
+			result.Action = action
+			if len(route) > 0 {
+				result.Route = route[0]
+			}
+		}
+		return result
+	})
+}
+
+// RespondWith corta la ejecucion del grafo devolviendo reply en lugar de
+// llamar al modelo. Pensado para guardrails de entrada.
+func RespondWith(guardrail Guardrail, reply string) Guardrail {
+	return GuardrailFunc(func(ctx context.Context, bag *Bag[any], content string) GuardrailResult {
+		result := guardrail.Check(ctx, bag, content)
+		if result.Tripped {
+			result.Action = GuardrailRespond
+			result.Content = reply
+		}
+		return result
+	})
+}
+
+func DenyList(patterns ...string) Guardrail {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		regexps = append(regexps, regexp.MustCompile(pattern))
+	}
+
+	return GuardrailFunc(func(ctx context.Context, bag *Bag[any], content string) GuardrailResult {
+		rewritten := content
+		tripped := []string{}
+		for _, re := range regexps {
+			if re.MatchString(rewritten) {
+				tripped = append(tripped, re.String())
+				rewritten = re.ReplaceAllString(rewritten, "[redacted]")
+			}
+		}
+		if len(tripped) == 0 {
+			return GuardrailResult{}
+		}
+
+		return GuardrailResult{
+			Tripped: true,
+			Reason:  "content matches denied pattern " + strings.Join(tripped, ", "),
+			Action:  GuardrailBlock,
+			Content: rewritten,
+		}
+	})
+}
+
+func MaxLength(max int) Guardrail {
+	return GuardrailFunc(func(ctx context.Context, bag *Bag[any], content string) GuardrailResult {
+		runes := []rune(content)
+		if len(runes) <= max {
+			return GuardrailResult{}
+		}
+
+		return GuardrailResult{
+			Tripped: true,
+			Reason:  fmt.Sprintf("content is longer than %d characters", max),
+			Action:  GuardrailBlock,
+			Content: string(runes[:max]),
+		}
+	})
+}
+
+func ValidJSON() Guardrail {
+	return GuardrailFunc(func(ctx context.Context, bag *Bag[any], content string) GuardrailResult {
+		if json.Valid([]byte(strings.TrimSpace(content))) {
+			return GuardrailResult{}
+		}
+
+		return GuardrailResult{
+			Tripped: true,
+			Reason:  "content is not valid JSON",
+			Action:  GuardrailRetry,
+		}
+	})
+}
+
+func PromptInjection() Guardrail {
+	return DenyList(
+		`(?i)ignore (all |any )?(the )?(previous|prior|above) (instructions|prompts?)`,
+		`(?i)disregard (all |any )?(the )?(previous|prior|above) (instructions|prompts?)`,
+		`(?i)(reveal|show|print) (me )?(your|the) (system )?(prompt|instructions)`,
+		`(?i)you are no longer`,
+	)
+}
+
+var piiPatterns = map[string]*regexp.Regexp{
+	"email":       regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	"credit card": regexp.MustCompile(`\b(?:\d[ \-]?){13,16}\b`),
+	"phone":       regexp.MustCompile(`\+?\d[\d \-().]{7,}\d`),
+}
+
+func RedactPII() Guardrail {
+	return GuardrailFunc(func(ctx context.Context, bag *Bag[any], content string) GuardrailResult {
+		rewritten := content
+		found := []string{}
+		// Emails primero, despues tarjetas y al final telefonos: el patron de
+		// telefono tambien matchea una tarjeta y la taparia con "[phone]"
+		for _, kind := range []string{"email", "credit card", "phone"} {
+			re := piiPatterns[kind]
+			if re.MatchString(rewritten) {
+				found = append(found, kind)
+				rewritten = re.ReplaceAllString(rewritten, "["+kind+"]")
+			}
+		}
+		if len(found) == 0 {
+			return GuardrailResult{}
+		}
+
+		return GuardrailResult{
+			Tripped: true,
+			Reason:  "content contains " + strings.Join(found, ", "),
+			Action:  GuardrailRewrite,
+			Content: rewritten,
+		}
+	})
+}
+
+type namedGuardrail struct {
+	name      string
+	guardrail Guardrail
+}
+
+func (a *Agent) resolveGuardrails(names []string) []namedGuardrail {
+	result := []namedGuardrail{}
+	for _, name := range names {
+		g, ok := getGuardrail(name)
+		if !ok {
+			withDiagnostic(Diagnostic{
+				Severity: SeverityError,
+				Code:     DiagUnregisteredGuardrail,
+				Message:  fmt.Sprintf("guardrail %q is not registered", name),
+			})(a)
+			continue
+		}
+		result = append(result, namedGuardrail{name, g})
+	}
+	return result
+}
+
+// checkGuardrails aplica los rewrites en orden y devuelve el primer guardrail
+// que se disparo con otra accion.
+func checkGuardrails(ctx context.Context, bag *Bag[any], guardrails []namedGuardrail, content string) (string, *GuardrailError, *GuardrailResult) {
+	for _, g := range guardrails {
+		result := g.guardrail.Check(ctx, bag, content)
+		if !result.Tripped {
+			continue
+		}
+		if result.Action == GuardrailRewrite {
+			content = result.Content
+			continue
+		}
+		return content, &GuardrailError{Guardrail: g.name, Reason: result.Reason}, &result
+	}
+
+	return content, nil, nil
+}
@@ -0,0 +1,142 @@
+package agentics
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const handoffPrefix = "transfer_to_"
+
+// HandoffKey es la clave del Bag donde queda el Handoff hasta que el agente
+// destino termina su vuelta.
+const HandoffKey = "handoff"
+
+// Handoff es el pase de un agente a otro que pidio el modelo llamando a la
+// tool transfer_to_<agente>. El agente destino lo recibe en el Bag y como
+// mensaje de sistema al final de la conversacion; ese mensaje no se guarda en
+// la memoria. Cuando el destino responde el pase se borra del Bag.
+type Handoff struct {
+	From    string                 `json:"from"`
+	To      string                 `json:"to"`
+	Reason  string                 `json:"reason,omitempty"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+func (h Handoff) String() string {
+	message := fmt.Sprintf("Handoff from %s to %s.", h.From, h.To)
+	if h.Reason != "" {
+		message += " Reason: " + h.Reason
+	}
+	if len(h.Payload) > 0 {
+		payload, _ := json.Marshal(h.Payload)
+		message += "\nPayload: " + string(payload)
+	}
+	return message
+}
+
+// Las tools de handoff no se ejecutan: el agente las intercepta antes. Un
+// agente con branches tambien puede terminar el grafo con transfer_to_END.
+func handoffTools(branchs []string) []ToolInterface {
+	tools := []ToolInterface{}
+	if len(branchs) == 0 {
+		return tools
+	}
+
+	params := []DescriptionParams{
+		{Name: "reason", Type: "string", Description: "Why the conversation is transferred."},
+		{Name: "payload", Type: "object", Description: "Data the next agent needs to continue."},
+	}
+	for _, branch := range branchs {
+		if branch == Exitpoint {
+			continue
+		}
+		tools = append(tools, NewTool(
+			handoffPrefix+branch,
+			"Transfer the conversation to the "+branch+" agent.",
+			params,
+			nil,
+		))
+	}
+	tools = append(tools, NewTool(
+		handoffPrefix+Exitpoint,
+		"Finish the conversation. Use it when no other agent needs to act.",
+		params[:1],
+		nil,
+	))
+	return tools
+}
+
+func (a *Agent) isHandoff(toolCall ToolCall) bool {
+	to, ok := strings.CutPrefix(toolCall.Name, handoffPrefix)
+	return ok && len(a.Branchs) > 0 && (to == Exitpoint || slices.Contains(a.Branchs, to))
+}
+
+func (a *Agent) findHandoff(toolCalls []ToolCall) *Handoff {
+	for _, toolCall := range toolCalls {
+		if !a.isHandoff(toolCall) {
+			continue
+		}
+
+		to := strings.TrimPrefix(toolCall.Name, handoffPrefix)
+		handoff := &Handoff{From: a.Name, To: to}
+		if toolCall.Arguments != "" {
+			if err := json.Unmarshal([]byte(toolCall.Arguments), handoff); err != nil {
+				fmt.Println("Error unmarshalling handoff arguments:", err)
+			}
+			handoff.From, handoff.To = a.Name, to
+		}
+		return handoff
+	}
+
+	return nil
+}
+
+// handoffFor devuelve el Handoff del Bag si es para agent. Despues de un
+// checkpoint el valor vuelve como map.
+func handoffFor(bag *Bag[any], agent string) *Handoff {
+	var handoff Handoff
+	switch value := bag.Get(HandoffKey).(type) {
+	case Handoff:
+		handoff = value
+	case *Handoff:
+		if value == nil {
+			return nil
+		}
+		handoff = *value
+	case map[string]interface{}:
+		data, err := json.Marshal(value)
+		if err != nil || json.Unmarshal(data, &handoff) != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	if handoff.To != agent {
+		return nil
+	}
+	return &handoff
+}
+
+// rejectHandoffs arma los resultados de las tools de handoff de una vuelta
+// que tambien pidio otras tools: el pase se hace recien cuando el modelo vio
+// esos resultados y lo vuelve a pedir solo.
+func (a *Agent) rejectHandoffs(toolCalls []ToolCall) ([]Message, []ToolCall) {
+	rejected := []Message{}
+	others := []ToolCall{}
+	for _, toolCall := range toolCalls {
+		if !a.isHandoff(toolCall) {
+			others = append(others, toolCall)
+			continue
+		}
+		rejected = append(rejected, Message{
+			Role:       "tool",
+			Content:    "Not transferred: call " + toolCall.Name + " on its own, after reading the results of the other tools.",
+			ToolCallID: toolCall.ToolCallID,
+			IsError:    true,
+		})
+	}
+	return rejected, others
+}
@@ -0,0 +1,175 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func handoffGraph(router *fakeProvider, billing *fakeProvider, routerOptions ...AgentOption) *Graph {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddAgent(NewAgent("router", "", append([]AgentOption{withFake(router), WithBranchs([]string{"billing"})}, routerOptions...)...))
+	g.AddAgent(NewAgent("billing", "", withFake(billing)))
+	g.SetEntrypoint("router")
+	g.Mem.Add("user", "me cobraron dos veces")
+	return g
+}
+
+func TestHandoff(t *testing.T) {
+	router := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(ToolCall{Name: "transfer_to_billing", Arguments: `{"reason":"cobro doble","payload":{"order":"42"}}`, ToolCallID: "call_1"}),
+	}}
+	billing := &fakeProvider{responses: []*ModelResponse{text("Te devolvemos el cobro")}}
+	var received interface{}
+	RegisterHook("test_read_handoff", func(ctx context.Context, c *Context) error {
+		received = c.Bag.Get(HandoffKey)
+		return nil
+	})
+	g := handoffGraph(router, billing)
+	g.AddAgent(NewAgent("billing", "", withFake(billing), WithHooks(PreHook, "test_read_handoff")))
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.Results); got != "router,billing" {
+		t.Fatalf("results = %s", got)
+	}
+
+	// billing lo lee del Bag mientras corre y despues se borra
+	handoff, ok := received.(Handoff)
+	if !ok || handoff.To != "billing" || handoff.Reason != "cobro doble" || handoff.Payload["order"] != "42" {
+		t.Fatalf("handoff = %#v", received)
+	}
+	if value := response.Bag.Get(HandoffKey); value != nil {
+		t.Fatalf("handoff = %#v after billing answered, want it cleared", value)
+	}
+
+	// billing ve el pase como ultimo mensaje, pero no queda en la memoria
+	seen := billing.call(0)
+	if last := seen[len(seen)-1]; last.Role != "system" || !strings.Contains(last.Content, "Reason: cobro doble") {
+		t.Fatalf("billing messages = %+v", seen)
+	}
+	for _, message := range response.Mem.All() {
+		if message.Role == "system" {
+			t.Fatalf("memory = %+v, want no handoff message", response.Mem.All())
+		}
+	}
+
+	// El router puede terminar el grafo
+	var offered []string
+	for _, tool := range handoffTools([]string{"billing"}) {
+		offered = append(offered, tool.GetName())
+	}
+	if strings.Join(offered, ",") != "transfer_to_billing,transfer_to_END" {
+		t.Fatalf("handoff tools = %v", offered)
+	}
+}
+
+func TestHandoffIsReadOnce(t *testing.T) {
+	router := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(ToolCall{Name: "transfer_to_billing", Arguments: `{"reason":"cobro doble"}`, ToolCallID: "call_1"}),
+		text("Te paso con facturacion"),
+	}}
+	billing := &fakeProvider{responses: []*ModelResponse{text("Te devolvemos el cobro"), text("Algo mas?")}}
+	g := handoffGraph(router, billing)
+	g.AddRelation("router", "billing")
+
+	if _, err := g.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Segunda corrida con el mismo Bag: billing llega por la relacion, sin pase
+	if _, err := g.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if billing.callCount() != 2 {
+		t.Fatalf("billing calls = %d, want 2", billing.callCount())
+	}
+	for _, message := range billing.call(1) {
+		if message.Role == "system" && strings.Contains(message.Content, "Handoff from") {
+			t.Fatalf("second visit got the old handoff: %+v", billing.call(1))
+		}
+	}
+}
+
+func TestHandoffToEnd(t *testing.T) {
+	router := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(ToolCall{Name: "transfer_to_END", Arguments: `{"reason":"resuelto"}`, ToolCallID: "call_1"}),
+	}}
+	billing := &fakeProvider{}
+	g := handoffGraph(router, billing)
+	g.AddRelation("router", "billing")
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.Results); got != "router" || billing.callCount() != 0 {
+		t.Fatalf("results = %s, billing calls = %d", got, billing.callCount())
+	}
+}
+
+func TestHandoffWithOtherTools(t *testing.T) {
+	router := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(
+			ToolCall{Name: "weather", Arguments: `{"city":"Madrid"}`, ToolCallID: "call_1"},
+			ToolCall{Name: "transfer_to_billing", Arguments: `{}`, ToolCallID: "call_2"},
+		),
+		toolCalls(ToolCall{Name: "transfer_to_billing", Arguments: `{}`, ToolCallID: "call_3"}),
+	}}
+	billing := &fakeProvider{responses: []*ModelResponse{text("listo")}}
+	calls := []string{}
+	g := handoffGraph(router, billing, WithTools([]ToolInterface{weatherTool(&calls)}))
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.Results); got != "router,billing" || len(calls) != 1 {
+		t.Fatalf("results = %s, weather calls = %v", got, calls)
+	}
+
+	// La segunda vuelta ve el resultado de la tool y el pase rechazado
+	results := map[string]Message{}
+	for _, message := range router.call(1) {
+		if message.Role == "tool" {
+			results[message.ToolCallID] = message
+		}
+	}
+	if results["call_1"].Content != "soleado" || !results["call_2"].IsError {
+		t.Fatalf("tool results = %+v", results)
+	}
+}
+
+func TestHandoffOutputGuardrails(t *testing.T) {
+	RegisterGuardrail("test_handoff_deny", DenyList(`(?i)tarjeta`))
+
+	router := &fakeProvider{responses: []*ModelResponse{{
+		Content:    "Escribime a ana@example.com",
+		IsToolCall: true,
+		ToolCalls:  []ToolCall{{Name: "transfer_to_billing", Arguments: `{}`, ToolCallID: "call_1"}},
+	}}}
+	billing := &fakeProvider{responses: []*ModelResponse{text("listo")}}
+	g := handoffGraph(router, billing, WithOutputGuardrails([]string{"redact_pii", "test_handoff_deny"}))
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Results[0].Content != "Escribime a [email]" {
+		t.Fatalf("router content = %q, want it redacted", response.Results[0].Content)
+	}
+
+	router = &fakeProvider{responses: []*ModelResponse{{
+		Content:    "Pasame el numero de tarjeta",
+		IsToolCall: true,
+		ToolCalls:  []ToolCall{{Name: "transfer_to_billing", Arguments: `{}`, ToolCallID: "call_1"}},
+	}}}
+	billing = &fakeProvider{}
+	g = handoffGraph(router, billing, WithOutputGuardrails([]string{"test_handoff_deny"}))
+
+	if _, err := g.Run(context.Background()); !errors.Is(err, ErrGuardrailTripped) || billing.callCount() != 0 {
+		t.Fatalf("err = %v, billing calls = %d, want the handoff blocked", err, billing.callCount())
+	}
+}
@@ -0,0 +1,181 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+var ErrInterrupted = errors.New("run interrupted")
+
+// answersKey guarda en el Bag las respuestas que todavia no consumio
+// AskHuman, por Key. Asi un valor que ya estaba en el Bag bajo Key no se
+// toma como respuesta.
+const answersKey = "__answers"
+
+// Interrupt es el pedido de intervencion humana que deja una corrida en
+// pausa. La respuesta se guarda en el Bag bajo Key al reanudar. Si pregunto
+// un sub-grafo, Child es el estado de su corrida, que sigue desde ahi.
+type Interrupt struct {
+	RunID    string      `json:"run_id"`
+	Agent    string      `json:"agent"`
+	Key      string      `json:"key"`
+	Question string      `json:"question"`
+	Turn     *Turn       `json:"turn,omitempty"`
+	Child    *Checkpoint `json:"child,omitempty"`
+}
+
+// Turn es lo que el agente ya habia hecho en la vuelta que se interrumpio:
+// los mensajes del loop de tools y las tool calls que faltan. Al reanudar el
+// agente sigue desde ahi, sin volver a llamar al modelo ni a las tools que
+// ya terminaron. Finished indica que pregunto un post hook: la respuesta
+// (Content y NextAgent) ya se guardo y solo vuelven a correr los post hooks.
+type Turn struct {
+	Agent     string     `json:"agent"`
+	Messages  []Message  `json:"messages"`
+	Pending   []ToolCall `json:"pending,omitempty"`
+	Finished  bool       `json:"finished,omitempty"`
+	Content   string     `json:"content,omitempty"`
+	NextAgent string     `json:"next_agent,omitempty"`
+}
+
+type turnKey struct{}
+
+type resumedTurn struct {
+	mu   sync.Mutex
+	turn *Turn
+}
+
+func withTurn(ctx context.Context, turn *Turn) context.Context {
+	return context.WithValue(ctx, turnKey{}, &resumedTurn{turn: turn})
+}
+
+// takeTurn devuelve la vuelta guardada para agent una sola vez: si el agente
+// vuelve a correr en la misma corrida arranca de cero.
+func takeTurn(ctx context.Context, agent string) *Turn {
+	resumed, ok := ctx.Value(turnKey{}).(*resumedTurn)
+	if !ok {
+		return nil
+	}
+
+	resumed.mu.Lock()
+	defer resumed.mu.Unlock()
+	if resumed.turn == nil || resumed.turn.Agent != agent {
+		return nil
+	}
+	turn := resumed.turn
+	resumed.turn = nil
+	return turn
+}
+
+type childKey struct{}
+
+type resumedChild struct {
+	mu         sync.Mutex
+	node       string
+	checkpoint *Checkpoint
+}
+
+func withChild(ctx context.Context, node string, checkpoint *Checkpoint) context.Context {
+	return context.WithValue(ctx, childKey{}, &resumedChild{node: node, checkpoint: checkpoint})
+}
+
+// takeChild devuelve una sola vez la corrida en pausa del sub-grafo node.
+func takeChild(ctx context.Context, node string) *Checkpoint {
+	resumed, ok := ctx.Value(childKey{}).(*resumedChild)
+	if !ok {
+		return nil
+	}
+
+	resumed.mu.Lock()
+	defer resumed.mu.Unlock()
+	if resumed.checkpoint == nil || resumed.node != node {
+		return nil
+	}
+	checkpoint := resumed.checkpoint
+	resumed.checkpoint = nil
+	return checkpoint
+}
+
+type InterruptError struct {
+	Interrupt Interrupt
+}
+
+func (e *InterruptError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrInterrupted, e.Interrupt.Question)
+}
+
+func (e *InterruptError) Is(target error) bool {
+	return target == ErrInterrupted
+}
+
+// AskHuman devuelve la respuesta que trajo ResumeWithAnswer para key, y la
+// consume para que el proximo pedido vuelva a preguntar. Si todavia no hay
+// respuesta devuelve un *InterruptError: una tool o un hook lo devuelve tal
+// cual y el grafo se suspende hasta ResumeWithAnswer.
+func AskHuman(bag *Bag[any], key string, question string) (string, error) {
+	answers, _ := bag.Get(answersKey).(map[string]interface{})
+	if answer, ok := answers[key].(string); ok {
+		answers = maps.Clone(answers)
+		delete(answers, key)
+		if len(answers) == 0 {
+			bag.Delete(answersKey)
+		} else {
+			bag.Set(answersKey, answers)
+		}
+		return answer, nil
+	}
+
+	return "", &InterruptError{Interrupt: Interrupt{Key: key, Question: question}}
+}
+
+func setAnswer(bag *Bag[any], key string, answer string) {
+	answers, _ := bag.Get(answersKey).(map[string]interface{})
+	answers = maps.Clone(answers)
+	if answers == nil {
+		answers = make(map[string]interface{})
+	}
+	answers[key] = answer
+	bag.Set(answersKey, answers)
+	bag.Set(key, answer)
+}
+
+// HumanNode es un nodo del grafo que espera la respuesta de una persona, por
+// ejemplo para aprobar un reembolso. La respuesta se agrega a la memoria como
+// mensaje del usuario y queda como Content, asi un AddConditionalEdge puede
+// rutear segun lo que se contesto.
+type HumanNode struct {
+	Name     string
+	Question string
+	Key      string
+}
+
+func NewHumanNode(name string, question string) *HumanNode {
+	return &HumanNode{
+		Name:     name,
+		Question: question,
+		Key:      name,
+	}
+}
+
+func (h *HumanNode) Run(ctx context.Context, bag *Bag[any], mem Memory) AgentResponse {
+	answer, err := AskHuman(bag, h.Key, h.Question)
+	if err != nil {
+		return AgentResponse{Error: err}
+	}
+
+	mem.Add("user", answer)
+	return AgentResponse{Content: answer}
+}
+
+// ResumeWithAnswer reanuda una corrida suspendida por un Interrupt, guardando
+// answer en el Bag antes de volver a correr el agente que pregunto.
+func (g *Graph) ResumeWithAnswer(ctx context.Context, runID string, answer string) (*GraphResponse, error) {
+	return g.resume(ctx, runID, &answer, nil)
+}
+
+func (c *CompiledGraph) ResumeWithAnswer(ctx context.Context, runID string, answer string) (*GraphResponse, error) {
+	return c.graph.ResumeWithAnswer(ctx, runID, answer)
+}
@@ -0,0 +1,195 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestInterruptReplaysTheTurn(t *testing.T) {
+	provider := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(
+			ToolCall{Name: "lookup", Arguments: `{}`, ToolCallID: "call_1"},
+			ToolCall{Name: "refund", Arguments: `{}`, ToolCallID: "call_2"},
+		),
+		text("Reembolso hecho"),
+	}}
+	lookups := 0
+	lookup := NewTool("lookup", "", nil, func(ctx context.Context, bag *Bag[any], input *ToolParams) (interface{}, error) {
+		lookups++
+		return "orden 42", nil
+	})
+	refund := NewTool("refund", "", nil, func(ctx context.Context, bag *Bag[any], input *ToolParams) (interface{}, error) {
+		answer, err := AskHuman(bag, "approval", "Aprobar reembolso?")
+		if err != nil {
+			return nil, err
+		}
+		return "aprobado: " + answer, nil
+	})
+
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	// Un valor previo bajo la clave no es una respuesta
+	g.Bag.Set("approval", "")
+	g.AddAgent(NewAgent("support", "", withFake(provider), WithTools([]ToolInterface{lookup, refund})))
+	g.SetEntrypoint("support")
+	g.SetCheckpointer(NewMemoryCheckpointer())
+
+	response, err := g.Run(context.Background())
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("err = %v, want ErrInterrupted", err)
+	}
+	if response.Interrupt == nil || response.Interrupt.Key != "approval" || response.Interrupt.Agent != "support" {
+		t.Fatalf("interrupt = %+v", response.Interrupt)
+	}
+
+	response, err = g.ResumeWithAnswer(context.Background(), response.RunID, "si")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Results[0].Content != "Reembolso hecho" {
+		t.Fatalf("results = %+v", response.Results)
+	}
+
+	// Ni el modelo ni lookup vuelven a correr por el interrupt
+	if provider.callCount() != 2 || lookups != 1 {
+		t.Fatalf("model calls = %d, lookups = %d, want 2 and 1", provider.callCount(), lookups)
+	}
+	last := provider.call(1)
+	results := map[string]string{}
+	for _, message := range last {
+		if message.Role == "tool" {
+			results[message.ToolCallID] = message.Content
+		}
+	}
+	if results["call_1"] != "orden 42" || results["call_2"] != "aprobado: si" {
+		t.Fatalf("tool results = %v", results)
+	}
+	if response.Bag.Get("approval") != "si" {
+		t.Fatalf("approval = %v", response.Bag.Get("approval"))
+	}
+}
+
+func TestHumanNodeAsksEveryVisit(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("review", NewHumanNode("review", "Esta bien?"))
+	g.AddNode("done", reply("listo"))
+	g.AddRelation(Entrypoint, "review")
+	g.AddConditionalEdge("review", func(bag *Bag[any], response AgentResponse) string {
+		if response.Content == "si" {
+			return "done"
+		}
+		return "review"
+	}, "done", "review")
+	g.SetCyclic(10)
+	g.SetCheckpointer(NewMemoryCheckpointer())
+	ctx := context.Background()
+
+	response, err := g.Run(ctx)
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("err = %v, want ErrInterrupted", err)
+	}
+
+	// La primera respuesta no alcanza: el nodo vuelve a preguntar
+	response, err = g.ResumeWithAnswer(ctx, response.RunID, "no")
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("err = %v, want a second question", err)
+	}
+	response, err = g.ResumeWithAnswer(ctx, response.RunID, "si")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.Results); got != "review,done" {
+		t.Fatalf("results = %s", got)
+	}
+}
+
+func TestFanOutInterrupt(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	start := reply("inicio")
+	other := reply("otra")
+	g.AddNode("start", start)
+	g.AddNode("approve", NewHumanNode("approve", "Aprobar?"))
+	g.AddNode("other", other)
+	g.AddNode("after", reply("despues"))
+	g.AddRelation(Entrypoint, "start")
+	g.AddFanOut("start", Join{Name: "join", Branches: []string{"approve", "other"}})
+	g.AddRelation("join", "after")
+	g.SetCheckpointer(NewMemoryCheckpointer())
+	ctx := context.Background()
+
+	response, err := g.Run(ctx)
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("err = %v, want the interrupt from the branch", err)
+	}
+	if response.Interrupt == nil || response.Interrupt.Agent != "approve" {
+		t.Fatalf("interrupt = %+v", response.Interrupt)
+	}
+
+	response, err = g.ResumeWithAnswer(ctx, response.RunID, "si")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.Results); got != "approve,other,after" {
+		t.Fatalf("results = %s", got)
+	}
+	if start.count() != 1 || response.Bag.Get("approve") != "si" {
+		t.Fatalf("start ran %d times, approve = %v", start.count(), response.Bag.Get("approve"))
+	}
+}
+
+func TestValidateMissingCheckpointer(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("review", NewHumanNode("review", "Esta bien?"))
+	g.SetEntrypoint("review")
+
+	if !hasDiagnostic(g.Validate(), SeverityError, DiagMissingCheckpointer, "review") {
+		t.Fatalf("diagnostics = %v", g.Validate())
+	}
+
+	g.SetCheckpointer(NewMemoryCheckpointer())
+	if hasDiagnostic(g.Validate(), SeverityError, DiagMissingCheckpointer, "review") {
+		t.Fatalf("diagnostics = %v", g.Validate())
+	}
+}
+
+func TestPostHookInterrupt(t *testing.T) {
+	RegisterHook("test_confirm", func(ctx context.Context, c *Context) error {
+		answer, err := AskHuman(c.Bag, "confirm", "Enviar la respuesta?")
+		if err != nil {
+			return err
+		}
+		c.Bag.Set("confirmed", answer)
+		return nil
+	})
+	provider := &fakeProvider{responses: []*ModelResponse{text("Borrador listo")}}
+
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddAgent(NewAgent("writer", "", withFake(provider), WithHooks(PostHook, "test_confirm")))
+	g.AddNode("send", reply("enviado"))
+	g.AddRelation(Entrypoint, "writer")
+	g.AddRelation("writer", "send")
+	g.SetCheckpointer(NewMemoryCheckpointer())
+
+	response, err := g.Run(context.Background())
+	if !errors.Is(err, ErrInterrupted) || response.Interrupt == nil || response.Interrupt.Key != "confirm" {
+		t.Fatalf("err = %v, interrupt = %+v", err, response.Interrupt)
+	}
+	if names(response.Results) != "writer" {
+		t.Fatalf("results = %s, want the run to stop after writer", names(response.Results))
+	}
+
+	response, err = g.ResumeWithAnswer(context.Background(), response.RunID, "si")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// El modelo no vuelve a correr: solo el post hook
+	if provider.callCount() != 1 || response.Bag.Get("confirmed") != "si" {
+		t.Fatalf("model calls = %d, confirmed = %v", provider.callCount(), response.Bag.Get("confirmed"))
+	}
+	if names(response.Results) != "writer,send" || response.Results[0].Content != "Borrador listo" {
+		t.Fatalf("results = %+v", response.Results)
+	}
+	if got := response.Mem.ToArrayString(); len(got) != 2 || got[0] != "Borrador listo" || got[1] != "enviado" {
+		t.Fatalf("memory = %q", got)
+	}
+}
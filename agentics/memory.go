@@ -1,23 +1,76 @@
 package agentics
 
 import (
+	"context"
+	"net/http"
+	"strings"
 	"sync"
 )
 
 type Message struct {
-	Role       string // "system", "user", "assistant", "tool"
-	Content    string
-	ToolCallID string
-	ToolCalls  []ToolCall // For assistant messages with tool calls
+	Role       string     `json:"role"` // "system", "user", "assistant", "tool"
+	Content    string     `json:"content"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"` // For assistant messages with tool calls
+	IsError    bool       `json:"is_error,omitempty"`   // For tool messages whose tool failed
+	Data       []byte     `json:"data,omitempty"`       // Binary content, e.g. an image
+	MediaType  string     `json:"media_type,omitempty"` // MIME type of Data
 }
 
+func newMessage(role string, content string, toolCallID ...string) Message {
+	message := Message{
+		Role:    role,
+		Content: content,
+	}
+	if len(toolCallID) > 0 {
+		message.ToolCallID = toolCallID[0]
+	}
+	return message
+}
+
+// bytesMessage guarda el texto como Content y el resto (imagenes, PDFs) en
+// Data con su tipo detectado.
+func bytesMessage(role string, content []byte) Message {
+	mediaType := http.DetectContentType(content)
+	if strings.HasPrefix(mediaType, "text/") {
+		return Message{Role: role, Content: string(content)}
+	}
+
+	return Message{
+		Role:      role,
+		Data:      append([]byte{}, content...),
+		MediaType: mediaType,
+	}
+}
+
+// SessionMemory la implementan las memorias persistentes para que
+// Graph.NewSession abra la conversacion de cada sesion en el mismo backend.
+type SessionMemory interface {
+	Memory
+	ForSession(sessionID string) (Memory, error)
+}
+
+// SummarizingMemory la implementan las memorias que resumen lo que recortan.
+// El agente llama a Summarize antes de armar los mensajes para el modelo.
+type SummarizingMemory interface {
+	Memory
+	Summarize(ctx context.Context) error
+}
+
+// Memory es el historial de una conversacion. All, LastN y Snapshot devuelven
+// copias: modificarlas no cambia la memoria. El paquete memorytest tiene las
+// pruebas que tiene que pasar cualquier implementacion.
 type Memory interface {
 	Add(role string, content string, toolCallID ...string)
+	AddMessage(message Message)
+	AddBytes(role string, content []byte)
 	LastN(n int) []Message
 	All() []Message
 	Len() int
 	ToArrayString() []string
-	AddBytes(string, []byte)
+	Clear()
+	Snapshot() []Message
+	Restore(messages []Message)
 }
 
 type SliceMemory struct {
@@ -34,25 +87,7 @@ func NewSliceMemory(max int) *SliceMemory {
 }
 
 func (m *SliceMemory) Add(role string, content string, toolCallID ...string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if len(toolCallID) > 0 {
-		m.data = append(m.data, Message{
-			Role:       role,
-			Content:    content,
-			ToolCallID: toolCallID[0],
-		})
-	} else {
-		m.data = append(m.data, Message{
-			Role:    role,
-			Content: content,
-		})
-	}
-
-	if len(m.data) > m.max {
-		m.data = m.data[len(m.data)-m.max:]
-	}
+	m.AddMessage(newMessage(role, content, toolCallID...))
 }
 
 func (m *SliceMemory) AddMessage(message Message) {
@@ -60,28 +95,32 @@ func (m *SliceMemory) AddMessage(message Message) {
 	defer m.mu.Unlock()
 
 	m.data = append(m.data, message)
+	m.trim()
+}
 
-	if len(m.data) > m.max {
-		m.data = m.data[len(m.data)-m.max:]
-	}
+func (m *SliceMemory) AddBytes(role string, content []byte) {
+	m.AddMessage(bytesMessage(role, content))
 }
 
 func (m *SliceMemory) LastN(n int) []Message {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	if n < 0 {
+		n = 0
+	}
 	if n > len(m.data) {
-		return m.data
+		n = len(m.data)
 	}
 
-	return m.data[len(m.data)-n:]
+	return copyMessages(m.data[len(m.data)-n:])
 }
 
 func (m *SliceMemory) All() []Message {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return m.data
+	return copyMessages(m.data)
 }
 
 func (m *SliceMemory) ToArrayString() []string {
@@ -103,11 +142,43 @@ func (m *SliceMemory) Len() int {
 	return len(m.data)
 }
 
-func (m *SliceMemory) AddBytes(role string, content []byte) {
+func (m *SliceMemory) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.data = []Message{}
+}
+
+func (m *SliceMemory) Snapshot() []Message {
+	return m.All()
+}
+
+func (m *SliceMemory) Restore(messages []Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data = copyMessages(messages)
+	m.trim()
+}
+
+func (m *SliceMemory) trim() {
 	if len(m.data) > m.max {
 		m.data = m.data[len(m.data)-m.max:]
 	}
 }
+
+// copyMessages copia tambien los slices de cada mensaje, para que quien
+// recibe la copia no pueda tocar la memoria.
+func copyMessages(messages []Message) []Message {
+	result := make([]Message, len(messages))
+	for i, message := range messages {
+		if message.ToolCalls != nil {
+			message.ToolCalls = append([]ToolCall{}, message.ToolCalls...)
+		}
+		if message.Data != nil {
+			message.Data = append([]byte{}, message.Data...)
+		}
+		result[i] = message
+	}
+	return result
+}
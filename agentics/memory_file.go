@@ -0,0 +1,201 @@
+package agentics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileMemory guarda cada mensaje como una linea JSON en <dir>/<session>.jsonl.
+// Solo Clear y Restore reescriben el archivo; max limita los mensajes que se
+// devuelven, no los que se guardan: Snapshot devuelve el historial completo.
+type FileMemory struct {
+	mu        sync.RWMutex
+	dir       string
+	sessionID string
+	max       int
+	data      []Message
+}
+
+func NewFileMemory(dir string, sessionID string, max int) (*FileMemory, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	m := &FileMemory{
+		dir:       dir,
+		sessionID: sessionID,
+		max:       max,
+		data:      []Message{},
+	}
+
+	file, err := os.Open(m.path())
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var message Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			fmt.Println("Error reading memory line:", err)
+			continue
+		}
+		m.data = append(m.data, message)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *FileMemory) ForSession(sessionID string) (Memory, error) {
+	return NewFileMemory(m.dir, sessionID, m.max)
+}
+
+func (m *FileMemory) Add(role string, content string, toolCallID ...string) {
+	m.AddMessage(newMessage(role, content, toolCallID...))
+}
+
+func (m *FileMemory) AddMessage(message Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.append(message); err != nil {
+		fmt.Println("Error writing memory:", err)
+	}
+	m.data = append(m.data, message)
+}
+
+func (m *FileMemory) AddBytes(role string, content []byte) {
+	m.AddMessage(bytesMessage(role, content))
+}
+
+func (m *FileMemory) LastN(n int) []Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	window := m.window()
+	if n < 0 {
+		n = 0
+	}
+	if n > len(window) {
+		n = len(window)
+	}
+	return copyMessages(window[len(window)-n:])
+}
+
+func (m *FileMemory) All() []Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyMessages(m.window())
+}
+
+func (m *FileMemory) ToArrayString() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var arr []string
+	for _, message := range m.window() {
+		arr = append(arr, message.Content)
+	}
+
+	return arr
+}
+
+func (m *FileMemory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.window())
+}
+
+func (m *FileMemory) Clear() {
+	m.Restore(nil)
+}
+
+// Snapshot devuelve todo lo guardado, no solo los ultimos max, para que
+// Restore(Snapshot()) no recorte el archivo.
+func (m *FileMemory) Snapshot() []Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyMessages(m.data)
+}
+
+// Restore reescribe el archivo de la sesion con messages.
+func (m *FileMemory) Restore(messages []Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.rewrite(messages); err != nil {
+		fmt.Println("Error writing memory:", err)
+	}
+	m.data = copyMessages(messages)
+}
+
+func (m *FileMemory) rewrite(messages []Message) error {
+	tmp, err := os.CreateTemp(m.dir, filepath.Base(m.sessionID)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	for _, message := range messages {
+		line, err := json.Marshal(message)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), m.path())
+}
+
+func (m *FileMemory) append(message Message) error {
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(m.path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// window son los mensajes que se devuelven: los ultimos max.
+func (m *FileMemory) window() []Message {
+	if m.max > 0 && len(m.data) > m.max {
+		return m.data[len(m.data)-m.max:]
+	}
+	return m.data
+}
+
+func (m *FileMemory) path() string {
+	return filepath.Join(m.dir, filepath.Base(m.sessionID)+".jsonl")
+}
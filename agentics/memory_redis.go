@@ -0,0 +1,281 @@
+package agentics
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisMemory guarda los mensajes de una sesion en una lista de Redis
+// (agentics:memory:<session>). Habla RESP directamente, asi que sirve contra
+// cualquier servidor compatible. La lista se recorta a max mensajes.
+type RedisMemory struct {
+	mu        sync.Mutex
+	Addr      string
+	SessionID string
+	max       int
+	conn      net.Conn
+	reader    *bufio.Reader
+}
+
+func NewRedisMemory(addr string, sessionID string, max int) (*RedisMemory, error) {
+	m := &RedisMemory{
+		Addr:      addr,
+		SessionID: sessionID,
+		max:       max,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.do("PING"); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *RedisMemory) ForSession(sessionID string) (Memory, error) {
+	return NewRedisMemory(m.Addr, sessionID, m.max)
+}
+
+func (m *RedisMemory) Add(role string, content string, toolCallID ...string) {
+	m.AddMessage(newMessage(role, content, toolCallID...))
+}
+
+func (m *RedisMemory) AddMessage(message Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		fmt.Println("Error writing memory:", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.do("RPUSH", m.key(), string(data)); err != nil {
+		fmt.Println("Error writing memory:", err)
+		return
+	}
+	if m.max > 0 {
+		if _, err := m.do("LTRIM", m.key(), strconv.Itoa(-m.max), "-1"); err != nil {
+			fmt.Println("Error writing memory:", err)
+		}
+	}
+}
+
+func (m *RedisMemory) AddBytes(role string, content []byte) {
+	m.AddMessage(bytesMessage(role, content))
+}
+
+func (m *RedisMemory) Clear() {
+	m.Restore(nil)
+}
+
+func (m *RedisMemory) Snapshot() []Message {
+	return m.All()
+}
+
+// Restore reemplaza la lista de la sesion. No es atomico: entre el DEL y el
+// RPUSH otro cliente puede ver la lista vacia.
+func (m *RedisMemory) Restore(messages []Message) {
+	args := []string{"RPUSH", m.key()}
+	for _, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			fmt.Println("Error writing memory:", err)
+			return
+		}
+		args = append(args, string(data))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.do("DEL", m.key()); err != nil {
+		fmt.Println("Error writing memory:", err)
+		return
+	}
+	if len(messages) == 0 {
+		return
+	}
+	if _, err := m.do(args...); err != nil {
+		fmt.Println("Error writing memory:", err)
+		return
+	}
+	if m.max > 0 {
+		if _, err := m.do("LTRIM", m.key(), strconv.Itoa(-m.max), "-1"); err != nil {
+			fmt.Println("Error writing memory:", err)
+		}
+	}
+}
+
+func (m *RedisMemory) LastN(n int) []Message {
+	if n <= 0 {
+		return []Message{}
+	}
+	return m.lrange(strconv.Itoa(-n))
+}
+
+func (m *RedisMemory) All() []Message {
+	return m.lrange("0")
+}
+
+func (m *RedisMemory) ToArrayString() []string {
+	var arr []string
+	for _, message := range m.All() {
+		arr = append(arr, message.Content)
+	}
+
+	return arr
+}
+
+func (m *RedisMemory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reply, err := m.do("LLEN", m.key())
+	if err != nil {
+		fmt.Println("Error reading memory:", err)
+		return 0
+	}
+	n, _ := reply.(int64)
+	return int(n)
+}
+
+func (m *RedisMemory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn == nil {
+		return nil
+	}
+	err := m.conn.Close()
+	m.conn = nil
+	return err
+}
+
+func (m *RedisMemory) lrange(start string) []Message {
+	m.mu.Lock()
+	reply, err := m.do("LRANGE", m.key(), start, "-1")
+	m.mu.Unlock()
+	if err != nil {
+		fmt.Println("Error reading memory:", err)
+		return []Message{}
+	}
+
+	items, _ := reply.([]interface{})
+	messages := make([]Message, 0, len(items))
+	for _, item := range items {
+		data, _ := item.(string)
+		var message Message
+		if err := json.Unmarshal([]byte(data), &message); err != nil {
+			fmt.Println("Error reading memory:", err)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func (m *RedisMemory) key() string {
+	return "agentics:memory:" + m.SessionID
+}
+
+// do manda un comando y lee la respuesta. Si falla la conexion se cierra y
+// el proximo comando vuelve a conectar. Se llama con mu tomado.
+func (m *RedisMemory) do(args ...string) (interface{}, error) {
+	if m.conn == nil {
+		conn, err := net.DialTimeout("tcp", m.Addr, 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		m.conn = conn
+		m.reader = bufio.NewReader(conn)
+	}
+
+	reply, err := m.command(args)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		m.conn.Close()
+		m.conn = nil
+	}
+	return reply, err
+}
+
+func (m *RedisMemory) command(args []string) (interface{}, error) {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := m.conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return readReply(m.reader)
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: invalid reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := readReply(r)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("redis: invalid reply %q", line)
+}
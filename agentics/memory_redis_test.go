@@ -0,0 +1,238 @@
+package agentics
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRedis atiende en el proceso los comandos de lista que usa RedisMemory.
+// errorReply, si no es vacio, se devuelve una vez como respuesta de error.
+type fakeRedis struct {
+	mu         sync.Mutex
+	lists      map[string][]string
+	conns      []net.Conn
+	errorReply string
+}
+
+func startFakeRedis(t *testing.T) (string, *fakeRedis) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeRedis{lists: make(map[string][]string)}
+	t.Cleanup(func() {
+		listener.Close()
+		server.dropConnections()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.mu.Lock()
+			server.conns = append(server.conns, conn)
+			server.mu.Unlock()
+			go server.serve(conn)
+		}
+	}()
+
+	return listener.Addr().String(), server
+}
+
+func (s *fakeRedis) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		request, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		args := []string{}
+		for _, arg := range request.([]interface{}) {
+			args = append(args, arg.(string))
+		}
+		if _, err := conn.Write([]byte(s.handle(args))); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedis) handle(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.errorReply != "" {
+		reply := "-" + s.errorReply + "\r\n"
+		s.errorReply = ""
+		return reply
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "RPUSH":
+		s.lists[args[1]] = append(s.lists[args[1]], args[2:]...)
+		return ":" + strconv.Itoa(len(s.lists[args[1]])) + "\r\n"
+	case "LTRIM":
+		s.lists[args[1]] = redisRange(s.lists[args[1]], args[2], args[3])
+		return "+OK\r\n"
+	case "LRANGE":
+		items := redisRange(s.lists[args[1]], args[2], args[3])
+		reply := "*" + strconv.Itoa(len(items)) + "\r\n"
+		for _, item := range items {
+			reply += "$" + strconv.Itoa(len(item)) + "\r\n" + item + "\r\n"
+		}
+		return reply
+	case "LLEN":
+		return ":" + strconv.Itoa(len(s.lists[args[1]])) + "\r\n"
+	case "DEL":
+		delete(s.lists, args[1])
+		return ":1\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func redisRange(list []string, start string, stop string) []string {
+	index := func(value string) int {
+		i, _ := strconv.Atoi(value)
+		if i < 0 {
+			i += len(list)
+		}
+		return i
+	}
+	from, to := max(index(start), 0), min(index(stop), len(list)-1)
+	if from > to {
+		return []string{}
+	}
+	return append([]string{}, list[from:to+1]...)
+}
+
+func TestRedisMemory(t *testing.T) {
+	addr, server := startFakeRedis(t)
+	mem, err := NewRedisMemory(addr, "ana", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+
+	for _, content := range []string{"uno", "dos", "tres", "cuatro"} {
+		mem.Add("user", content)
+	}
+	if got := strings.Join(mem.ToArrayString(), ","); got != "dos,tres,cuatro" || mem.Len() != 3 {
+		t.Fatalf("messages = %s, len = %d", got, mem.Len())
+	}
+	if last := mem.LastN(1); len(last) != 1 || last[0].Content != "cuatro" {
+		t.Fatalf("last = %+v", last)
+	}
+
+	// Otra sesion es otra lista en el mismo servidor
+	other, err := mem.ForSession("beto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.(*RedisMemory).Close()
+	other.Add("user", "hola")
+	server.mu.Lock()
+	stored := len(server.lists["agentics:memory:ana"])
+	server.mu.Unlock()
+	if other.Len() != 1 || stored != 3 {
+		t.Fatalf("other len = %d, ana stored = %d", other.Len(), stored)
+	}
+
+	mem.Restore([]Message{{Role: "user", Content: "restaurado"}})
+	if got := strings.Join(mem.ToArrayString(), ","); got != "restaurado" {
+		t.Fatalf("messages = %s", got)
+	}
+	mem.Clear()
+	if mem.Len() != 0 {
+		t.Fatalf("len = %d after Clear", mem.Len())
+	}
+}
+
+func TestRedisMemoryErrors(t *testing.T) {
+	addr, server := startFakeRedis(t)
+	mem, err := NewRedisMemory(addr, "ana", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	mem.Add("user", "hola")
+
+	// Un error de Redis no corta la conexion
+	server.mu.Lock()
+	server.errorReply = "WRONGTYPE Operation against a key holding the wrong kind of value"
+	server.mu.Unlock()
+	if all := mem.All(); len(all) != 0 {
+		t.Fatalf("messages = %+v, want none after an error reply", all)
+	}
+	if mem.conn == nil {
+		t.Fatal("connection closed after an error reply")
+	}
+
+	// Si se cae la conexion el proximo comando vuelve a conectar
+	server.dropConnections()
+	mem.All()
+	if all := mem.All(); len(all) != 1 || all[0].Content != "hola" {
+		t.Fatalf("messages = %+v after reconnecting", all)
+	}
+
+	if _, err := NewRedisMemory("127.0.0.1:1", "ana", 0); err == nil {
+		t.Fatal("expected an error without a server")
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  interface{}
+		err   string
+	}{
+		{"+OK\r\n", "OK", ""},
+		{":42\r\n", int64(42), ""},
+		{"$4\r\nhola\r\n", "hola", ""},
+		{"$-1\r\n", nil, ""},
+		{"*-1\r\n", nil, ""},
+		{"-ERR wrong type\r\n", nil, "redis: ERR wrong type"},
+		{"?what\r\n", nil, "invalid reply"},
+		{"+OK\n", nil, "invalid reply"},
+	}
+	for _, tt := range tests {
+		got, err := readReply(bufio.NewReader(strings.NewReader(tt.reply)))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: err = %v, want %q", tt.reply, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %#v, %v, want %#v", tt.reply, got, err, tt.want)
+		}
+	}
+
+	var redisErr redisError
+	if _, err := readReply(bufio.NewReader(strings.NewReader("-ERR\r\n"))); !errors.As(err, &redisErr) {
+		t.Fatalf("err = %v, want a redisError", err)
+	}
+
+	got, err := readReply(bufio.NewReader(strings.NewReader("*3\r\n$1\r\na\r\n$-1\r\n:1\r\n")))
+	items, _ := got.([]interface{})
+	if err != nil || len(items) != 3 || items[0] != "a" || items[1] != nil || items[2] != int64(1) {
+		t.Fatalf("array = %#v, %v", got, err)
+	}
+}
@@ -0,0 +1,190 @@
+package agentics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// SQLMemory guarda los mensajes de una sesion en una tabla usando
+// database/sql. El driver lo elige quien lo usa (por ejemplo SQLite); las
+// queries usan placeholders "?". Se guarda todo el historial; max solo
+// limita los mensajes que se devuelven.
+type SQLMemory struct {
+	DB        *sql.DB
+	Table     string
+	SessionID string
+	max       int
+}
+
+func NewSQLMemory(ctx context.Context, db *sql.DB, table string, sessionID string, max int) (*SQLMemory, error) {
+	if table == "" {
+		table = "agentics_messages"
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id TEXT NOT NULL,
+		data TEXT NOT NULL
+	)`, table)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return nil, err
+	}
+
+	return &SQLMemory{
+		DB:        db,
+		Table:     table,
+		SessionID: sessionID,
+		max:       max,
+	}, nil
+}
+
+func (m *SQLMemory) ForSession(sessionID string) (Memory, error) {
+	return &SQLMemory{
+		DB:        m.DB,
+		Table:     m.Table,
+		SessionID: sessionID,
+		max:       m.max,
+	}, nil
+}
+
+func (m *SQLMemory) Add(role string, content string, toolCallID ...string) {
+	m.AddMessage(newMessage(role, content, toolCallID...))
+}
+
+func (m *SQLMemory) AddMessage(message Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		fmt.Println("Error writing memory:", err)
+		return
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (session_id, data) VALUES (?, ?)", m.Table)
+	if _, err := m.DB.Exec(query, m.SessionID, string(data)); err != nil {
+		fmt.Println("Error writing memory:", err)
+	}
+}
+
+func (m *SQLMemory) AddBytes(role string, content []byte) {
+	m.AddMessage(bytesMessage(role, content))
+}
+
+func (m *SQLMemory) Clear() {
+	m.Restore(nil)
+}
+
+// Snapshot devuelve todo el historial de la sesion, no solo los ultimos max,
+// para que Restore(Snapshot()) no borre mensajes.
+func (m *SQLMemory) Snapshot() []Message {
+	return m.last(m.count())
+}
+
+// Restore reemplaza todos los mensajes de la sesion en una transaccion.
+func (m *SQLMemory) Restore(messages []Message) {
+	if err := m.restore(messages); err != nil {
+		fmt.Println("Error writing memory:", err)
+	}
+}
+
+func (m *SQLMemory) restore(messages []Message) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE session_id = ?", m.Table), m.SessionID); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (session_id, data) VALUES (?, ?)", m.Table)
+	for _, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(query, m.SessionID, string(data)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *SQLMemory) LastN(n int) []Message {
+	if m.max > 0 && n > m.max {
+		n = m.max
+	}
+	return m.last(n)
+}
+
+func (m *SQLMemory) last(n int) []Message {
+	if n <= 0 {
+		return []Message{}
+	}
+
+	query := fmt.Sprintf("SELECT data FROM %s WHERE session_id = ? ORDER BY id DESC LIMIT ?", m.Table)
+	rows, err := m.DB.Query(query, m.SessionID, n)
+	if err != nil {
+		fmt.Println("Error reading memory:", err)
+		return []Message{}
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var data string
+		var message Message
+		if err := rows.Scan(&data); err != nil {
+			fmt.Println("Error reading memory:", err)
+			continue
+		}
+		if err := json.Unmarshal([]byte(data), &message); err != nil {
+			fmt.Println("Error reading memory:", err)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Println("Error reading memory:", err)
+	}
+
+	// Vienen del mas nuevo al mas viejo
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages
+}
+
+func (m *SQLMemory) All() []Message {
+	if m.max > 0 {
+		return m.LastN(m.max)
+	}
+	return m.LastN(m.count())
+}
+
+func (m *SQLMemory) ToArrayString() []string {
+	var arr []string
+	for _, message := range m.All() {
+		arr = append(arr, message.Content)
+	}
+
+	return arr
+}
+
+func (m *SQLMemory) Len() int {
+	n := m.count()
+	if m.max > 0 && n > m.max {
+		return m.max
+	}
+	return n
+}
+
+func (m *SQLMemory) count() int {
+	var n int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE session_id = ?", m.Table)
+	if err := m.DB.QueryRow(query, m.SessionID).Scan(&n); err != nil {
+		fmt.Println("Error reading memory:", err)
+	}
+	return n
+}
@@ -0,0 +1,73 @@
+package agentics_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/parisote/agentics/agentics"
+	"github.com/parisote/agentics/agentics/memorytest"
+)
+
+func TestMemoryBackends(t *testing.T) {
+	dir := t.TempDir()
+	db := agentics.OpenTestDB(t)
+	redis := agentics.StartFakeRedis(t)
+
+	// Los backends persistentes usan una sesion nueva por memoria para que
+	// cada prueba arranque vacia
+	session := 0
+	next := func() string {
+		session++
+		return fmt.Sprintf("session-%d", session)
+	}
+
+	backends := map[string]func() (agentics.Memory, error){
+		"slice": func() (agentics.Memory, error) {
+			return agentics.NewSliceMemory(20), nil
+		},
+		"tokens": func() (agentics.Memory, error) {
+			return agentics.NewTokenMemory(100000), nil
+		},
+		"summary": func() (agentics.Memory, error) {
+			return agentics.NewSummaryMemory(nil, 100000), nil
+		},
+		"file": func() (agentics.Memory, error) {
+			return agentics.NewFileMemory(dir, next(), 20)
+		},
+		"redis": func() (agentics.Memory, error) {
+			return agentics.NewRedisMemory(redis, next(), 20)
+		},
+	}
+	if db != nil {
+		backends["sql"] = func() (agentics.Memory, error) {
+			return agentics.NewSQLMemory(context.Background(), db, "", next(), 20)
+		}
+	}
+	for name, newMemory := range backends {
+		t.Run(name, func(t *testing.T) {
+			if err := memorytest.TestMemory(newMemory); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// File y SQL guardan todo aunque devuelvan los ultimos max
+	history := map[string]func(max int) (agentics.Memory, error){
+		"file": func(max int) (agentics.Memory, error) {
+			return agentics.NewFileMemory(dir, next(), max)
+		},
+	}
+	if db != nil {
+		history["sql"] = func(max int) (agentics.Memory, error) {
+			return agentics.NewSQLMemory(context.Background(), db, "", next(), max)
+		}
+	}
+	for name, newMemory := range history {
+		t.Run(name+"/history", func(t *testing.T) {
+			if err := memorytest.TestHistory(newMemory); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package agentics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ventana de contexto aproximada por prefijo de modelo. El mas largo gana.
+var contextWindows = map[string]int{
+	"gpt-3.5-turbo": 16385,
+	"gpt-4":         8192,
+	"gpt-4-turbo":   128000,
+	"gpt-4o":        128000,
+	"gpt-4.1":       1047576,
+	"o1":            200000,
+	"o3":            200000,
+	"o4":            200000,
+	"claude":        200000,
+}
+
+// ContextWindow devuelve la ventana de contexto conocida para model, o 8192
+// si no se conoce.
+func ContextWindow(model string) int {
+	window, length := 8192, 0
+	for prefix, tokens := range contextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > length {
+			window, length = tokens, len(prefix)
+		}
+	}
+	return window
+}
+
+// EstimateTokens es una estimacion barata (~4 caracteres por token) que no
+// depende del tokenizer de cada proveedor.
+func EstimateTokens(message Message) int {
+	chars := len(message.Content)
+	for _, toolCall := range message.ToolCalls {
+		chars += len(toolCall.Name) + len(toolCall.Arguments)
+	}
+	tokens := chars/4 + 4
+	if len(message.Data) > 0 {
+		// Una imagen cuesta del orden de mil tokens, no su tamano en bytes
+		tokens += 1000
+	}
+	return tokens
+}
+
+// TokenMemory recorta por presupuesto de tokens en lugar de cantidad de
+// mensajes. Los mensajes de sistema no se recortan nunca.
+type TokenMemory struct {
+	mu        sync.RWMutex
+	maxTokens int
+	data      []Message
+	Estimate  func(Message) int
+	onEvict   func([]Message)
+}
+
+func NewTokenMemory(maxTokens int) *TokenMemory {
+	return &TokenMemory{
+		maxTokens: maxTokens,
+		data:      []Message{},
+		Estimate:  EstimateTokens,
+	}
+}
+
+// NewTokenMemoryForModel usa la mitad de la ventana de contexto del modelo,
+// el resto queda para el prompt, las tools y la respuesta.
+func NewTokenMemoryForModel(model string) *TokenMemory {
+	return NewTokenMemory(ContextWindow(model) / 2)
+}
+
+func (m *TokenMemory) Add(role string, content string, toolCallID ...string) {
+	m.AddMessage(newMessage(role, content, toolCallID...))
+}
+
+func (m *TokenMemory) AddMessage(message Message) {
+	m.mu.Lock()
+	m.data = append(m.data, message)
+	evicted := m.trim()
+	onEvict := m.onEvict
+	m.mu.Unlock()
+
+	if onEvict != nil && len(evicted) > 0 {
+		onEvict(evicted)
+	}
+}
+
+func (m *TokenMemory) AddBytes(role string, content []byte) {
+	m.AddMessage(bytesMessage(role, content))
+}
+
+func (m *TokenMemory) LastN(n int) []Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if n < 0 {
+		n = 0
+	}
+	if n > len(m.data) {
+		n = len(m.data)
+	}
+	return copyMessages(m.data[len(m.data)-n:])
+}
+
+func (m *TokenMemory) All() []Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyMessages(m.data)
+}
+
+func (m *TokenMemory) ToArrayString() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var arr []string
+	for _, message := range m.data {
+		arr = append(arr, message.Content)
+	}
+
+	return arr
+}
+
+func (m *TokenMemory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.data)
+}
+
+func (m *TokenMemory) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data = []Message{}
+}
+
+func (m *TokenMemory) Snapshot() []Message {
+	return m.All()
+}
+
+// Restore no llama a onEvict: lo que no entra en el presupuesto se descarta.
+func (m *TokenMemory) Restore(messages []Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data = copyMessages(messages)
+	m.trim()
+}
+
+func (m *TokenMemory) Tokens() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.tokens()
+}
+
+func (m *TokenMemory) tokens() int {
+	total := 0
+	for _, message := range m.data {
+		total += m.Estimate(message)
+	}
+	return total
+}
+
+// trim saca los mensajes mas viejos que no son de sistema hasta entrar en el
+// presupuesto, siempre dejando el ultimo. Tambien saca los resultados de
+// tools que quedaron sin el mensaje que los pidio.
+func (m *TokenMemory) trim() []Message {
+	evicted := []Message{}
+	total := m.tokens()
+
+	for total > m.maxTokens || m.orphanTool() {
+		i := m.oldest()
+		if i < 0 || i == len(m.data)-1 {
+			break
+		}
+		total -= m.Estimate(m.data[i])
+		evicted = append(evicted, m.data[i])
+		m.data = append(m.data[:i], m.data[i+1:]...)
+	}
+
+	return evicted
+}
+
+func (m *TokenMemory) oldest() int {
+	for i, message := range m.data {
+		if message.Role != "system" {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *TokenMemory) orphanTool() bool {
+	i := m.oldest()
+	return i >= 0 && m.data[i].Role == "tool"
+}
+
+const summaryPrefix = "Summary of the earlier conversation: "
+
+const defaultSummaryTimeout = 30 * time.Second
+
+// SummaryMemory es una TokenMemory que, en lugar de perder los mensajes que
+// recorta, los resume con Client en un resumen que se va acumulando y que se
+// devuelve como primer mensaje de sistema. Agregar mensajes no llama al
+// modelo: lo recortado queda pendiente, tal cual, hasta que Summarize lo
+// resume. El agente llama a Summarize antes de cada corrida. Sin Client no
+// hay resumen y lo recortado se pierde, como en TokenMemory.
+type SummaryMemory struct {
+	mu      sync.RWMutex
+	window  *TokenMemory
+	Client  *ModelClient
+	summary string
+	pending []Message
+	// Timeout limita cada llamada al modelo que resume. Por defecto 30s.
+	Timeout time.Duration
+	// summarizing evita dos resumenes a la vez sobre lo mismo
+	summarizing sync.Mutex
+}
+
+func NewSummaryMemory(client *ModelClient, maxTokens int) *SummaryMemory {
+	m := &SummaryMemory{
+		window:  NewTokenMemory(maxTokens),
+		Client:  client,
+		Timeout: defaultSummaryTimeout,
+	}
+	m.window.onEvict = m.evict
+
+	return m
+}
+
+func (m *SummaryMemory) Add(role string, content string, toolCallID ...string) {
+	m.window.Add(role, content, toolCallID...)
+}
+
+func (m *SummaryMemory) AddMessage(message Message) {
+	m.window.AddMessage(message)
+}
+
+func (m *SummaryMemory) AddBytes(role string, content []byte) {
+	m.window.AddBytes(role, content)
+}
+
+func (m *SummaryMemory) LastN(n int) []Message {
+	return m.window.LastN(n)
+}
+
+// All devuelve el resumen, lo pendiente de resumir y la ventana.
+func (m *SummaryMemory) All() []Message {
+	m.mu.RLock()
+	messages := []Message{}
+	if m.summary != "" {
+		messages = append(messages, Message{
+			Role:    "system",
+			Content: summaryPrefix + m.summary,
+		})
+	}
+	messages = append(messages, copyMessages(m.pending)...)
+	m.mu.RUnlock()
+
+	return append(messages, m.window.All()...)
+}
+
+func (m *SummaryMemory) ToArrayString() []string {
+	var arr []string
+	for _, message := range m.All() {
+		arr = append(arr, message.Content)
+	}
+
+	return arr
+}
+
+func (m *SummaryMemory) Len() int {
+	m.mu.RLock()
+	n := len(m.pending)
+	if m.summary != "" {
+		n++
+	}
+	m.mu.RUnlock()
+
+	return n + m.window.Len()
+}
+
+func (m *SummaryMemory) Clear() {
+	m.mu.Lock()
+	m.summary = ""
+	m.pending = nil
+	m.mu.Unlock()
+
+	m.window.Clear()
+}
+
+func (m *SummaryMemory) Snapshot() []Message {
+	return m.All()
+}
+
+// Restore recupera el resumen si el primer mensaje es el que arma All.
+func (m *SummaryMemory) Restore(messages []Message) {
+	summary := ""
+	if len(messages) > 0 && messages[0].Role == "system" && strings.HasPrefix(messages[0].Content, summaryPrefix) {
+		summary = strings.TrimPrefix(messages[0].Content, summaryPrefix)
+		messages = messages[1:]
+	}
+
+	m.mu.Lock()
+	m.summary = summary
+	m.pending = nil
+	m.mu.Unlock()
+
+	m.window.Restore(messages)
+}
+
+func (m *SummaryMemory) Summary() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.summary
+}
+
+func (m *SummaryMemory) evict(evicted []Message) {
+	if !m.canSummarize() {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pending = append(m.pending, evicted...)
+}
+
+func (m *SummaryMemory) canSummarize() bool {
+	return m.Client != nil && m.Client.provider != nil
+}
+
+// Summarize suma al resumen los mensajes pendientes. La llamada al modelo se
+// hace sin tomar el lock, asi la memoria se puede seguir leyendo y
+// escribiendo. Si falla, los mensajes siguen pendientes y se reintenta en la
+// proxima llamada.
+func (m *SummaryMemory) Summarize(ctx context.Context) error {
+	m.summarizing.Lock()
+	defer m.summarizing.Unlock()
+
+	m.mu.RLock()
+	summary := m.summary
+	pending := copyMessages(m.pending)
+	m.mu.RUnlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// Sin modelo lo pendiente se descarta: guardarlo crudo como resumen
+	// crece sin limite
+	if !m.canSummarize() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.pending = m.pending[min(len(pending), len(m.pending)):]
+		return nil
+	}
+
+	var transcript strings.Builder
+	if summary != "" {
+		transcript.WriteString("Previous summary: " + summary + "\n\n")
+	}
+	for _, message := range pending {
+		transcript.WriteString(message.Role + ": " + message.Content + "\n")
+	}
+
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = defaultSummaryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	response, err := m.Client.provider.Execute(
+		ctx,
+		"Summarize the conversation below in a few sentences. Keep names, the customer's problem, decisions taken and anything still pending.",
+		[]Message{{Role: "user", Content: transcript.String()}},
+		nil,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("summarize memory: %w", err)
+	}
+	summary = response.GetContent()
+
+	// Mientras se resumia pudieron llegar mas mensajes pendientes
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.summary = summary
+	m.pending = m.pending[min(len(pending), len(m.pending)):]
+	return nil
+}
@@ -0,0 +1,165 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextWindow(t *testing.T) {
+	tests := map[string]int{
+		"gpt-4":                    8192,
+		"gpt-4o-mini":              128000,
+		"gpt-4.1-nano":             1047576,
+		"claude-3-5-sonnet-latest": 200000,
+		"unknown":                  8192,
+	}
+	for model, want := range tests {
+		if got := ContextWindow(model); got != want {
+			t.Errorf("%s: got %d, want %d", model, got, want)
+		}
+	}
+}
+
+func tenTokens(Message) int { return 10 }
+
+func TestTokenMemoryTrim(t *testing.T) {
+	mem := NewTokenMemory(30)
+	mem.Estimate = tenTokens
+
+	mem.Add("system", "reglas")
+	mem.Add("user", "uno")
+	mem.AddMessage(Message{Role: "assistant", ToolCalls: []ToolCall{{Name: "weather", ToolCallID: "call_1"}}})
+	mem.Add("tool", "soleado", "call_1")
+	mem.Add("user", "dos")
+
+	// El de sistema queda siempre, y el resultado de la tool no queda sin
+	// el mensaje que lo pidio
+	if got := strings.Join(mem.ToArrayString(), ","); got != "reglas,dos" {
+		t.Fatalf("messages = %s", got)
+	}
+	if mem.Tokens() != 20 {
+		t.Fatalf("tokens = %d", mem.Tokens())
+	}
+}
+
+func newTestSummaryMemory(provider *fakeProvider) *SummaryMemory {
+	mem := NewSummaryMemory(&ModelClient{provider: provider}, 30)
+	mem.window.Estimate = tenTokens
+	return mem
+}
+
+func TestSummaryMemoryIsLazy(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	provider := &fakeProvider{respond: func(ctx context.Context, messages []Message) (*ModelResponse, error) {
+		close(started)
+		<-release
+		return text("Ana pidio un reembolso"), nil
+	}}
+	mem := newTestSummaryMemory(provider)
+
+	for _, content := range []string{"uno", "dos", "tres", "cuatro", "cinco"} {
+		mem.Add("user", content)
+	}
+	if provider.callCount() != 0 {
+		t.Fatal("adding messages called the model")
+	}
+	// Lo recortado sigue visible hasta que se resume
+	if got := strings.Join(mem.ToArrayString(), ","); got != "uno,dos,tres,cuatro,cinco" || mem.Len() != 5 {
+		t.Fatalf("messages = %s, len = %d", got, mem.Len())
+	}
+
+	done := make(chan error)
+	go func() { done <- mem.Summarize(context.Background()) }()
+	<-started
+
+	// Mientras el modelo resume la memoria se sigue usando
+	mem.Add("user", "seis")
+	if mem.Len() != 6 {
+		t.Fatalf("len = %d while summarizing", mem.Len())
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(provider.call(0)[0].Content, "user: uno\nuser: dos\n") {
+		t.Fatalf("transcript = %q", provider.call(0)[0].Content)
+	}
+
+	// "tres" se recorto durante el resumen y queda para el proximo
+	all := mem.All()
+	if all[0].Content != summaryPrefix+"Ana pidio un reembolso" || all[1].Content != "tres" || len(all) != 5 {
+		t.Fatalf("messages = %+v", all)
+	}
+
+	restored := newTestSummaryMemory(&fakeProvider{})
+	restored.Restore(mem.Snapshot())
+	if restored.Summary() != "Ana pidio un reembolso" {
+		t.Fatalf("summary = %q after Restore", restored.Summary())
+	}
+}
+
+func TestSummaryMemoryErrors(t *testing.T) {
+	provider := &fakeProvider{respond: func(ctx context.Context, messages []Message) (*ModelResponse, error) {
+		return nil, errors.New("rate limited")
+	}}
+	mem := newTestSummaryMemory(provider)
+	for _, content := range []string{"uno", "dos", "tres", "cuatro"} {
+		mem.Add("user", content)
+	}
+
+	if err := mem.Summarize(context.Background()); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Fatalf("err = %v", err)
+	}
+	if mem.Summary() != "" || mem.Len() != 4 {
+		t.Fatalf("summary = %q, len = %d, want the messages kept", mem.Summary(), mem.Len())
+	}
+
+	// Si no se pudo resumir el agente sigue con los mensajes sin resumir
+	model := &fakeProvider{responses: []*ModelResponse{text("hola")}}
+	response := NewAgent("support", "", withFake(model)).Run(context.Background(), NewBag[any](), mem)
+	if response.Error != nil || model.callCount() != 1 {
+		t.Fatalf("err = %v, model calls = %d", response.Error, model.callCount())
+	}
+	seen := []string{}
+	for _, message := range model.call(0) {
+		seen = append(seen, message.Content)
+	}
+	if got := strings.Join(seen, ","); got != "uno,dos,tres,cuatro" {
+		t.Fatalf("model saw %s", got)
+	}
+
+	slow := &fakeProvider{respond: func(ctx context.Context, messages []Message) (*ModelResponse, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	mem = newTestSummaryMemory(slow)
+	mem.Timeout = 10 * time.Millisecond
+	for _, content := range []string{"uno", "dos", "tres", "cuatro"} {
+		mem.Add("user", content)
+	}
+	if err := mem.Summarize(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the timeout", err)
+	}
+}
+
+func TestSummaryMemoryWithoutClient(t *testing.T) {
+	mem := NewSummaryMemory(nil, 30)
+	mem.window.Estimate = tenTokens
+	for i := 0; i < 50; i++ {
+		mem.Add("user", fmt.Sprintf("mensaje %d", i))
+	}
+	if err := mem.Summarize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sin modelo no hay resumen: lo recortado se pierde como en TokenMemory
+	if mem.Summary() != "" || mem.Len() != 3 {
+		t.Fatalf("summary = %q, len = %d", mem.Summary(), mem.Len())
+	}
+}
@@ -0,0 +1,277 @@
+// Package memorytest prueba implementaciones de agentics.Memory, al estilo de
+// testing/fstest.
+package memorytest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/parisote/agentics/agentics"
+)
+
+// png minimo: alcanza para que http.DetectContentType devuelva image/png.
+var pngBytes = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01")
+
+// TestMemory corre las pruebas de conformidad contra memorias nuevas creadas
+// con newMemory, que tienen que empezar vacias y guardar al menos 10
+// mensajes. Devuelve todos los fallos juntos, o nil.
+//
+// Uso desde un test:
+//
+//	if err := memorytest.TestMemory(func() (agentics.Memory, error) {
+//		return agentics.NewSliceMemory(20), nil
+//	}); err != nil {
+//		t.Fatal(err)
+//	}
+func TestMemory(newMemory func() (agentics.Memory, error)) error {
+	checks := []struct {
+		name string
+		run  func(agentics.Memory) error
+	}{
+		{"empty", testEmpty},
+		{"add", testAdd},
+		{"lastN", testLastN},
+		{"copies", testCopies},
+		{"bytes", testBytes},
+		{"clear", testClear},
+		{"snapshot", testSnapshot},
+		{"overflow", testOverflow},
+	}
+
+	var errs []error
+	for _, check := range checks {
+		mem, err := newMemory()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: new memory: %w", check.name, err))
+			continue
+		}
+		if err := check.run(mem); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", check.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// TestHistory prueba memorias que guardan mas mensajes de los que devuelven,
+// como FileMemory o SQLMemory: max limita All, LastN y Len, pero Snapshot
+// devuelve todo y Restore(Snapshot()) no pierde nada. newMemory crea
+// memorias vacias que devuelven como mucho max mensajes.
+func TestHistory(newMemory func(max int) (agentics.Memory, error)) error {
+	mem, err := newMemory(2)
+	if err != nil {
+		return fmt.Errorf("new memory: %w", err)
+	}
+
+	messages := numbered(6)
+	for _, message := range messages {
+		mem.AddMessage(message)
+	}
+	if n := mem.Len(); n != 2 {
+		return fmt.Errorf("Len() = %d, want 2", n)
+	}
+	if err := expect("All()", mem.All(), messages[4:]); err != nil {
+		return err
+	}
+	if err := expect("LastN(5)", mem.LastN(5), messages[4:]); err != nil {
+		return err
+	}
+	if err := expect("Snapshot()", mem.Snapshot(), messages); err != nil {
+		return err
+	}
+
+	mem.Restore(mem.Snapshot())
+	if err := expect("Snapshot() after Restore(Snapshot())", mem.Snapshot(), messages); err != nil {
+		return err
+	}
+
+	mem.Restore(messages[:3])
+	if err := expect("Snapshot() after Restore", mem.Snapshot(), messages[:3]); err != nil {
+		return err
+	}
+	return expect("All() after Restore", mem.All(), messages[1:3])
+}
+
+func numbered(n int) []agentics.Message {
+	messages := []agentics.Message{}
+	for i := 0; i < n; i++ {
+		messages = append(messages, agentics.Message{Role: "user", Content: fmt.Sprintf("mensaje %d", i)})
+	}
+	return messages
+}
+
+func conversation() []agentics.Message {
+	return []agentics.Message{
+		{Role: "user", Content: "hola"},
+		{Role: "assistant", ToolCalls: []agentics.ToolCall{
+			{Name: "weather", Arguments: `{"city":"Madrid"}`, ToolCallID: "call_1"},
+		}},
+		{Role: "tool", Content: "soleado", ToolCallID: "call_1"},
+		{Role: "assistant", Content: "Hace sol"},
+	}
+}
+
+func fill(mem agentics.Memory) {
+	messages := conversation()
+	mem.Add(messages[0].Role, messages[0].Content)
+	mem.AddMessage(messages[1])
+	mem.Add(messages[2].Role, messages[2].Content, messages[2].ToolCallID)
+	mem.AddMessage(messages[3])
+}
+
+func expect(what string, got, want []agentics.Message) error {
+	if len(got) == 0 && len(want) == 0 {
+		return nil
+	}
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("%s = %+v, want %+v", what, got, want)
+	}
+	return nil
+}
+
+func testEmpty(mem agentics.Memory) error {
+	if n := mem.Len(); n != 0 {
+		return fmt.Errorf("Len() = %d, want 0", n)
+	}
+	if err := expect("All()", mem.All(), nil); err != nil {
+		return err
+	}
+	return expect("LastN(3)", mem.LastN(3), nil)
+}
+
+func testAdd(mem agentics.Memory) error {
+	fill(mem)
+
+	if n := mem.Len(); n != 4 {
+		return fmt.Errorf("Len() = %d, want 4", n)
+	}
+	if err := expect("All()", mem.All(), conversation()); err != nil {
+		return err
+	}
+
+	want := []string{"hola", "", "soleado", "Hace sol"}
+	if got := mem.ToArrayString(); !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("ToArrayString() = %q, want %q", got, want)
+	}
+	return nil
+}
+
+func testLastN(mem agentics.Memory) error {
+	fill(mem)
+	messages := conversation()
+
+	if err := expect("LastN(2)", mem.LastN(2), messages[2:]); err != nil {
+		return err
+	}
+	if err := expect("LastN(10)", mem.LastN(10), messages); err != nil {
+		return err
+	}
+	if err := expect("LastN(0)", mem.LastN(0), nil); err != nil {
+		return err
+	}
+	return expect("LastN(-1)", mem.LastN(-1), nil)
+}
+
+func testCopies(mem agentics.Memory) error {
+	fill(mem)
+
+	all := mem.All()
+	if err := expect("All()", all, conversation()); err != nil {
+		return err
+	}
+	all[0].Content = "cambiado"
+	all[1].ToolCalls[0].Name = "cambiado"
+	if last := mem.LastN(1); len(last) == 1 {
+		last[0].Content = "cambiado"
+	}
+	if snapshot := mem.Snapshot(); len(snapshot) > 0 {
+		snapshot[0].Content = "cambiado"
+	}
+
+	return expect("All() after modifying returned slices", mem.All(), conversation())
+}
+
+func testBytes(mem agentics.Memory) error {
+	content := append([]byte{}, pngBytes...)
+	mem.AddBytes("user", []byte("texto plano"))
+	mem.AddBytes("user", content)
+	content[0] = 0
+
+	want := []agentics.Message{
+		{Role: "user", Content: "texto plano"},
+		{Role: "user", Data: pngBytes, MediaType: "image/png"},
+	}
+	got := mem.All()
+	if err := expect("All()", got, want); err != nil {
+		return err
+	}
+
+	if len(got) == 2 && len(got[1].Data) > 0 {
+		got[1].Data[0] = 0
+		return expect("All() after modifying returned data", mem.All(), want)
+	}
+	return nil
+}
+
+func testClear(mem agentics.Memory) error {
+	fill(mem)
+	mem.Clear()
+
+	if n := mem.Len(); n != 0 {
+		return fmt.Errorf("Len() after Clear = %d, want 0", n)
+	}
+	if err := expect("All() after Clear", mem.All(), nil); err != nil {
+		return err
+	}
+
+	mem.Add("user", "otra vez")
+	return expect("All() after Clear and Add", mem.All(), []agentics.Message{{Role: "user", Content: "otra vez"}})
+}
+
+func testSnapshot(mem agentics.Memory) error {
+	fill(mem)
+	snapshot := mem.Snapshot()
+	if err := expect("Snapshot()", snapshot, conversation()); err != nil {
+		return err
+	}
+
+	mem.Add("user", "despues del snapshot")
+	mem.Restore(snapshot)
+	if err := expect("All() after Restore", mem.All(), conversation()); err != nil {
+		return err
+	}
+
+	snapshot[0].Content = "cambiado"
+	if err := expect("All() after modifying restored slice", mem.All(), conversation()); err != nil {
+		return err
+	}
+
+	mem.Restore(nil)
+	if n := mem.Len(); n != 0 {
+		return fmt.Errorf("Len() after Restore(nil) = %d, want 0", n)
+	}
+	return nil
+}
+
+// testOverflow pasa el limite de la memoria: lo que devuelve tiene que ser el
+// final de Snapshot y Restore(Snapshot()) no puede perder mensajes.
+func testOverflow(mem agentics.Memory) error {
+	for _, message := range numbered(30) {
+		mem.AddMessage(message)
+	}
+	all := mem.All()
+	snapshot := mem.Snapshot()
+	if len(snapshot) < len(all) || len(all) == 0 {
+		return fmt.Errorf("Snapshot() has %d messages and All() %d", len(snapshot), len(all))
+	}
+	if err := expect("All()", all, snapshot[len(snapshot)-len(all):]); err != nil {
+		return err
+	}
+
+	mem.Restore(snapshot)
+	if err := expect("Snapshot() after Restore(Snapshot())", mem.Snapshot(), snapshot); err != nil {
+		return err
+	}
+	return expect("All() after Restore(Snapshot())", mem.All(), all)
+}
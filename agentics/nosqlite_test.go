@@ -0,0 +1,14 @@
+//go:build !cgo
+
+package agentics
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// Sin cgo no hay driver de SQLite: los tests de SQL se saltean.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Log("SQLite needs cgo, skipping the SQL backends")
+	return nil
+}
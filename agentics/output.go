@@ -0,0 +1,161 @@
+package agentics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type OutputSchema struct {
+	Name   string
+	Schema map[string]interface{}
+	goType reflect.Type
+}
+
+// OutputOf arma el schema a partir de un struct de Go, usando los mismos tags
+// que NewTypedTool. El valor decodificado en AgentResponse.Output es un T.
+func OutputOf[T any]() *OutputSchema {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic("output type must be a struct: " + t.String())
+	}
+
+	name := strings.ToLower(t.Name())
+	if name == "" {
+		name = "output"
+	}
+
+	return &OutputSchema{
+		Name:   name,
+		Schema: ParametersSchema(structParams(t)),
+		goType: t,
+	}
+}
+
+func NewOutputSchema(name string, schema map[string]interface{}) *OutputSchema {
+	return &OutputSchema{
+		Name:   name,
+		Schema: schema,
+	}
+}
+
+func (o *OutputSchema) Decode(content string) (interface{}, error) {
+	content = strings.TrimSpace(content)
+	// Algunos modelos envuelven el JSON en un bloque de codigo
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := validateSchema(o.Schema, value, "$"); err != nil {
+		return nil, err
+	}
+
+	if o.goType == nil {
+		return value, nil
+	}
+
+	typed := reflect.New(o.goType)
+	decoder := json.NewDecoder(bytes.NewReader([]byte(content)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(typed.Interface()); err != nil {
+		return nil, err
+	}
+
+	return typed.Elem().Interface(), nil
+}
+
+func validateSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		allowed := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(value) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%s must be one of %v", path, enum)
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for _, name := range schemaRequired(schema["required"]) {
+			if v, ok := object[name]; !ok || v == nil {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range properties {
+			propertySchema, ok := property.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if v, ok := object[name]; ok && v != nil {
+				if err := validateSchema(propertySchema, v, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array", path)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, v := range array {
+				if err := validateSchema(items, v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s must be a number", path)
+		}
+	case "integer":
+		if f, ok := value.(float64); !ok || f != float64(int64(f)) {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+	}
+
+	return nil
+}
+
+func schemaRequired(required interface{}) []string {
+	switch required := required.(type) {
+	case []string:
+		return required
+	case []interface{}:
+		result := []string{}
+		for _, r := range required {
+			if s, ok := r.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+
+	return nil
+}
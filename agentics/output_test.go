@@ -0,0 +1,55 @@
+package agentics
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestOutputSchemaDecode(t *testing.T) {
+	schema := OutputOf[forecastOutput]()
+
+	value, err := schema.Decode("```json\n{\"city\":\"Lima\",\"days\":2}\n```")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != (forecastOutput{City: "Lima", Days: 2}) {
+		t.Fatalf("value = %#v", value)
+	}
+
+	for content, want := range map[string]string{
+		`{"city":"Lima"}`:              "$.days is required",
+		`{"city":"Lima","days":"dos"}`: "$.days must be an integer",
+		`{"city":"Lima","days":2.5}`:   "$.days must be an integer",
+		`not json`:                     "invalid JSON",
+	} {
+		if _, err := schema.Decode(content); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestAgentRetriesInvalidOutput(t *testing.T) {
+	provider := &fakeProvider{responses: []*ModelResponse{
+		text(`{"city":"Lima"}`),
+		text(`{"city":"Lima","days":2}`),
+	}}
+	agent := NewAgent("forecast", "", withFake(provider),
+		WithOutputType(OutputOf[forecastOutput]()),
+		WithOutputKey("forecast"),
+	)
+	bag := NewBag[any]()
+
+	response := agent.Run(context.Background(), bag, NewSliceMemory(10))
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+	if response.Output != (forecastOutput{City: "Lima", Days: 2}) || bag.Get("forecast") != response.Output {
+		t.Fatalf("output = %#v, bag = %#v", response.Output, bag.Get("forecast"))
+	}
+
+	retry := provider.call(1)
+	if last := retry[len(retry)-1]; last.Role != "user" || !strings.Contains(last.Content, "$.days is required") {
+		t.Fatalf("retry message = %+v", last)
+	}
+}
@@ -0,0 +1,111 @@
+package agentics
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// Session es el estado de una corrida: su propio Bag y su propia Memory. El
+// Graph queda como definicion y se puede compartir entre goroutines, cada
+// caller usa su Session.
+type Session struct {
+	ID    string
+	Bag   *Bag[any]
+	Mem   Memory
+	graph *Graph
+}
+
+// NewSession arranca con una copia del Bag del grafo y una memoria vacia del
+// mismo tipo que Graph.Mem. Si id es vacio se genera uno. Falla si no se puede
+// abrir la memoria de la sesion.
+func (g *Graph) NewSession(id string) (*Session, error) {
+	if id == "" {
+		id = newSessionID()
+	}
+	mem, err := sessionMemory(g.Mem, id)
+	if err != nil {
+		return nil, err
+	}
+
+	bag := NewBag[any]()
+	if g.Bag != nil {
+		for k, v := range g.Bag.All() {
+			bag.Set(k, v)
+		}
+	}
+
+	return &Session{
+		ID:    id,
+		Bag:   bag,
+		Mem:   mem,
+		graph: g,
+	}, nil
+}
+
+func (c *CompiledGraph) NewSession(id string) (*Session, error) {
+	return c.graph.NewSession(id)
+}
+
+func (s *Session) Run(ctx context.Context) (*GraphResponse, error) {
+	return s.graph.start(ctx, s.ID, s.Bag, s.Mem, nil)
+}
+
+func (s *Session) RunStream(ctx context.Context, handler EventHandler) (*GraphResponse, error) {
+	return s.graph.start(ctx, s.ID, s.Bag, s.Mem, syncHandler(handler))
+}
+
+// sessionMemory abre la conversacion id en el mismo backend que mem. Las
+// memorias persistentes lo hacen con ForSession.
+func sessionMemory(mem Memory, id string) (Memory, error) {
+	if m, ok := mem.(SessionMemory); ok {
+		session, err := m.ForSession(id)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %w", id, err)
+		}
+		return session, nil
+	}
+	return newMemoryLike(mem)
+}
+
+// memorySessionID devuelve la sesion de una memoria persistente, o "" si la
+// memoria vive en el proceso.
+func memorySessionID(mem Memory) string {
+	switch m := mem.(type) {
+	case *FileMemory:
+		return m.sessionID
+	case *SQLMemory:
+		return m.SessionID
+	case *RedisMemory:
+		return m.SessionID
+	}
+	return ""
+}
+
+// newMemoryLike devuelve una memoria vacia con la misma configuracion que mem.
+// Una memoria que no se sabe copiar es un error: cambiarla por otra perderia
+// lo que la hace persistente o su limite.
+func newMemoryLike(mem Memory) (Memory, error) {
+	switch m := mem.(type) {
+	case nil:
+		return NewSliceMemory(10), nil
+	case *SliceMemory:
+		return NewSliceMemory(m.max), nil
+	case *TokenMemory:
+		fresh := NewTokenMemory(m.maxTokens)
+		fresh.Estimate = m.Estimate
+		return fresh, nil
+	case *SummaryMemory:
+		fresh := NewSummaryMemory(m.Client, m.window.maxTokens)
+		fresh.window.Estimate = m.window.Estimate
+		return fresh, nil
+	}
+	return nil, fmt.Errorf("memory %T cannot open another conversation: implement SessionMemory", mem)
+}
+
+func newSessionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
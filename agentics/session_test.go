@@ -0,0 +1,146 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSessionsRunConcurrently(t *testing.T) {
+	provider := &fakeProvider{respond: func(ctx context.Context, messages []Message) (*ModelResponse, error) {
+		return text("eco: " + messages[len(messages)-1].Content), nil
+	}}
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.Bag.Set("count", 0)
+	g.AddAgent(NewAgent("echo", "", withFake(provider)))
+	g.SetEntrypoint("echo")
+
+	var wg sync.WaitGroup
+	sessions := make([]*Session, 8)
+	for i := range sessions {
+		session, err := g.NewSession(fmt.Sprintf("s%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[i] = session
+		wg.Add(1)
+		go func(session *Session, i int) {
+			defer wg.Done()
+			session.Bag.Set("count", i)
+			session.Mem.Add("user", session.ID)
+			if _, err := session.Run(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}(sessions[i], i)
+	}
+	wg.Wait()
+
+	// Cada sesion ve solo su conversacion y su Bag
+	for i, session := range sessions {
+		all := session.Mem.All()
+		if len(all) != 2 || all[1].Content != "eco: "+session.ID {
+			t.Errorf("session %s memory = %+v", session.ID, all)
+		}
+		if session.Bag.Get("count") != i {
+			t.Errorf("session %s count = %v", session.ID, session.Bag.Get("count"))
+		}
+	}
+	if g.Mem.Len() != 0 || g.Bag.Get("count") != 0 {
+		t.Fatalf("graph state changed: mem %d, count %v", g.Mem.Len(), g.Bag.Get("count"))
+	}
+}
+
+func TestNewSessionGeneratesID(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	a, err := g.NewSession("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := g.NewSession("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID == "" || a.ID == b.ID {
+		t.Fatalf("ids = %q, %q", a.ID, b.ID)
+	}
+}
+
+// customMemory no implementa SessionMemory, asi que no se sabe abrir otra
+// conversacion con ella.
+type customMemory struct {
+	*SliceMemory
+}
+
+func TestSessionMemoryBackends(t *testing.T) {
+	file, err := NewFileMemory(t.TempDir(), "default", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGraph(NewBag[any](), file)
+
+	session, err := g.NewSession("ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mem, ok := session.Mem.(*FileMemory); !ok || mem.sessionID != "ana" {
+		t.Fatalf("session memory = %#v, want the file backend", session.Mem)
+	}
+
+	g.Mem = customMemory{NewSliceMemory(10)}
+	if _, err := g.NewSession("ana"); err == nil {
+		t.Fatal("expected an error for a memory that cannot open sessions")
+	}
+}
+
+func TestResumeKeepsPersistentMemory(t *testing.T) {
+	dir := t.TempDir()
+	file, err := NewFileMemory(dir, "default", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail := true
+	g := NewGraph(NewBag[any](), file)
+	g.AddNode("a", reply("uno"))
+	g.AddNode("b", &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		if fail {
+			return AgentResponse{Error: errors.New("caido")}
+		}
+		mem.Add("assistant", "dos")
+		return AgentResponse{Content: "dos"}
+	}})
+	g.AddRelation(Entrypoint, "a")
+	g.AddRelation("a", "b")
+	g.SetCheckpointer(NewMemoryCheckpointer())
+
+	response, err := g.Run(context.Background(), WithRunID("run-file"))
+	if err == nil {
+		t.Fatal("expected b to fail")
+	}
+
+	fail = false
+	response, err = g.Resume(context.Background(), "run-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem, ok := response.Mem.(*FileMemory)
+	if !ok || mem.sessionID != "default" {
+		t.Fatalf("memory = %#v, want the graph's file session", response.Mem)
+	}
+
+	// La conversacion sigue en la sesion original y no en una de la corrida
+	reopened, err := NewFileMemory(dir, "default", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(reopened.ToArrayString(), ","); got != "uno,dos" {
+		t.Fatalf("stored = %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run-file.jsonl")); !os.IsNotExist(err) {
+		t.Fatalf("resume wrote a session for the run id: %v", err)
+	}
+}
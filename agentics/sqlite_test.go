@@ -0,0 +1,22 @@
+//go:build cgo
+
+package agentics
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB abre una base SQLite temporal. El driver necesita cgo: sin cgo
+// los tests de SQL se saltean (ver nosqlite_test.go).
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
@@ -0,0 +1,47 @@
+package agentics
+
+import (
+	"context"
+	"sync"
+)
+
+type EventType string
+
+const (
+	EventAgentStarted     EventType = "agent_started"
+	EventAgentFinished    EventType = "agent_finished"
+	EventToken            EventType = "token"
+	EventToolCallStarted  EventType = "tool_call_started"
+	EventToolCallFinished EventType = "tool_call_finished"
+)
+
+type Event struct {
+	Type         EventType
+	Agent        string
+	Delta        string
+	ToolCall     *ToolCall
+	ToolResponse *ToolResponse
+	Response     *AgentResponse
+}
+
+type EventHandler func(Event)
+
+type StreamingAgent interface {
+	AgentInterface
+	RunStream(ctx context.Context, bag *Bag[any], mem Memory, handler EventHandler) AgentResponse
+}
+
+// Las tools pueden correr en paralelo, asi que el handler se serializa para
+// que quien lo implementa no tenga que preocuparse por la concurrencia.
+func syncHandler(handler EventHandler) EventHandler {
+	if handler == nil {
+		return func(Event) {}
+	}
+
+	var mu sync.Mutex
+	return func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		handler(e)
+	}
+}
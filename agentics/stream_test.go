@@ -0,0 +1,86 @@
+package agentics
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAgentRunStream(t *testing.T) {
+	provider := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(ToolCall{Name: "weather", Arguments: `{"city":"Madrid"}`, ToolCallID: "call_1"}),
+		text("Hace sol en Madrid"),
+	}}
+	calls := []string{}
+	agent := NewAgent("weather", "", withFake(provider), WithTools([]ToolInterface{weatherTool(&calls)}))
+
+	events := []Event{}
+	response := agent.RunStream(context.Background(), NewBag[any](), NewSliceMemory(10), func(e Event) {
+		events = append(events, e)
+	})
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+
+	types := []string{}
+	tokens := ""
+	for _, e := range events {
+		if e.Type == EventToken {
+			tokens += e.Delta
+			if len(types) > 0 && types[len(types)-1] == string(EventToken) {
+				continue
+			}
+		}
+		types = append(types, string(e.Type))
+	}
+	want := "agent_started,tool_call_started,tool_call_finished,token,agent_finished"
+	if got := strings.Join(types, ","); got != want {
+		t.Fatalf("events = %s, want %s", got, want)
+	}
+	if tokens != "Hace sol en Madrid" {
+		t.Fatalf("tokens = %q", tokens)
+	}
+	if finished := events[len(events)-1]; finished.Response == nil || finished.Response.Content != "Hace sol en Madrid" {
+		t.Fatalf("agent_finished = %+v", finished)
+	}
+}
+
+func TestGraphRunStreamOrder(t *testing.T) {
+	researcher := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(ToolCall{Name: "weather", Arguments: `{"city":"Madrid"}`, ToolCallID: "call_1"}),
+		text("Hace sol"),
+	}}
+	writer := &fakeProvider{responses: []*ModelResponse{text("Informe listo")}}
+	calls := []string{}
+
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddAgent(NewAgent("researcher", "", withFake(researcher), WithTools([]ToolInterface{weatherTool(&calls)})))
+	g.AddAgent(NewAgent("writer", "", withFake(writer)))
+	g.AddNode("archive", reply("archivado"))
+	g.AddRelation(Entrypoint, "researcher")
+	g.AddRelation("researcher", "writer")
+	g.AddRelation("writer", "archive")
+
+	events := []string{}
+	_, err := g.RunStream(context.Background(), func(e Event) {
+		event := string(e.Type) + ":" + e.Agent
+		if e.Type == EventToken && events[len(events)-1] == event {
+			return
+		}
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cada agente termina antes de que arranque el siguiente; un nodo que no
+	// es un agente igual avisa cuando empieza y termina
+	want := []string{
+		"agent_started:researcher", "tool_call_started:researcher", "tool_call_finished:researcher", "token:researcher", "agent_finished:researcher",
+		"agent_started:writer", "token:writer", "agent_finished:writer",
+		"agent_started:archive", "agent_finished:archive",
+	}
+	if got := strings.Join(events, ","); got != strings.Join(want, ",") {
+		t.Fatalf("events = %s", got)
+	}
+}
@@ -0,0 +1,228 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SubGraph permite usar un Graph como nodo de otro. El grafo hijo corre con
+// su propio Bag: Inputs copia claves del padre al hijo y Outputs del hijo al
+// padre. Si no hay mapeo se copian todas las claves.
+type SubGraph struct {
+	Name    string
+	Graph   *Graph
+	Inputs  map[string]string // clave del padre -> clave del hijo
+	Outputs map[string]string // clave del hijo -> clave del padre
+	// Con IsolatedMemory el hijo no ve la conversacion del padre, solo el
+	// ultimo mensaje, y al padre vuelve solo la respuesta final.
+	IsolatedMemory bool
+	diagnostics    []Diagnostic
+}
+
+type SubGraphOption func(*SubGraph)
+
+func WithInputs(inputs map[string]string) SubGraphOption {
+	return func(s *SubGraph) {
+		s.Inputs = inputs
+	}
+}
+
+func WithOutputs(outputs map[string]string) SubGraphOption {
+	return func(s *SubGraph) {
+		s.Outputs = outputs
+	}
+}
+
+func WithIsolatedMemory() SubGraphOption {
+	return func(s *SubGraph) {
+		s.IsolatedMemory = true
+	}
+}
+
+func NewSubGraph(name string, graph *Graph, options ...SubGraphOption) *SubGraph {
+	sub := &SubGraph{
+		Name:  name,
+		Graph: graph,
+	}
+
+	for _, option := range options {
+		option(sub)
+	}
+
+	return sub
+}
+
+func (g *Graph) AddSubGraph(name string, graph *Graph, options ...SubGraphOption) {
+	g.AddNode(name, NewSubGraph(name, graph, options...))
+}
+
+func (s *SubGraph) Run(ctx context.Context, bag *Bag[any], mem Memory) AgentResponse {
+	return s.run(ctx, bag, mem, nil)
+}
+
+func (s *SubGraph) RunStream(ctx context.Context, bag *Bag[any], mem Memory, handler EventHandler) AgentResponse {
+	return s.run(ctx, bag, mem, syncHandler(handler))
+}
+
+// running es la pila de grafos que estan corriendo en este contexto.
+type running struct {
+	graph  *Graph
+	parent *running
+}
+
+type runningKey struct{}
+
+func runningGraphs(ctx context.Context) *running {
+	r, _ := ctx.Value(runningKey{}).(*running)
+	return r
+}
+
+func (r *running) contains(g *Graph) bool {
+	for ; r != nil; r = r.parent {
+		if r.graph == g {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SubGraph) run(ctx context.Context, bag *Bag[any], mem Memory, emit EventHandler) AgentResponse {
+	if len(s.diagnostics) > 0 {
+		return AgentResponse{Error: &ValidationError{Diagnostics: s.diagnostics}}
+	}
+	if s.Graph == nil {
+		return AgentResponse{Error: errors.New("sub-graph " + s.Name + " has no graph")}
+	}
+	if runningGraphs(ctx).contains(s.Graph) {
+		return AgentResponse{Error: errors.New("sub-graph " + s.Name + " contains the graph that runs it")}
+	}
+
+	child := NewBag[any]()
+	values := bag.All()
+	cp := takeChild(ctx, s.Name)
+	if cp != nil {
+		// Se sigue la corrida del hijo que quedo en pausa, sin volver a correr
+		// los nodos que ya terminaron
+		for k, v := range cp.Bag {
+			child.Set(k, v)
+		}
+	} else {
+		queue, err := s.Graph.startNodes()
+		if err != nil {
+			return AgentResponse{Error: fmt.Errorf("sub-graph %s: %w", s.Name, err)}
+		}
+		cp = &Checkpoint{
+			RunID:  newSessionID(),
+			Queue:  queue,
+			Visits: make(map[string]int),
+		}
+
+		if s.Graph.Bag != nil {
+			for k, v := range s.Graph.Bag.All() {
+				child.Set(k, v)
+			}
+		}
+		if s.Inputs == nil {
+			for k, v := range values {
+				child.Set(k, v)
+			}
+		}
+		for from, to := range s.Inputs {
+			if v, ok := values[from]; ok {
+				child.Set(to, v)
+			}
+		}
+	}
+	if cp.Visits == nil {
+		cp.Visits = make(map[string]int)
+	}
+	// Las respuestas de ResumeWithAnswer pasan siempre, aunque Inputs no las
+	// nombre: las pide un AskHuman del hijo
+	if answers, ok := values[answersKey].(map[string]interface{}); ok {
+		child.Set(answersKey, answers)
+		for key := range answers {
+			child.Set(key, values[key])
+		}
+	}
+
+	childMem := mem
+	if s.IsolatedMemory {
+		session := cp.MemorySession
+		if session == "" {
+			session = cp.RunID
+		}
+		isolated, err := sessionMemory(s.Graph.Mem, session)
+		if err != nil {
+			return AgentResponse{Error: fmt.Errorf("sub-graph %s: %w", s.Name, err)}
+		}
+		childMem = isolated
+		if cp.Interrupt != nil {
+			childMem.Restore(cp.Messages)
+		} else if last := mem.LastN(1); len(last) > 0 {
+			childMem.AddMessage(last[0])
+		}
+	}
+
+	saved := -1
+	if cp.Interrupt != nil {
+		ctx, saved = resumeInterrupt(ctx, cp)
+	}
+	response, err := s.Graph.run(ctx, cp, child, childMem, emit, saved)
+
+	// El padre guarda la corrida del hijo en su Interrupt para seguirla al
+	// reanudar. Con memoria compartida los mensajes ya estan en la del padre
+	var interrupted *InterruptError
+	if errors.As(err, &interrupted) {
+		state := *cp
+		state.Bag = child.All()
+		state.Messages = nil
+		state.MemorySession = ""
+		if s.IsolatedMemory {
+			state.Messages = childMem.Snapshot()
+			state.MemorySession = memorySessionID(childMem)
+		}
+		err = &InterruptError{Interrupt: Interrupt{
+			Key:      interrupted.Interrupt.Key,
+			Question: interrupted.Interrupt.Question,
+			Child:    &state,
+		}}
+	}
+
+	childValues := child.All()
+	if s.Outputs == nil {
+		for k, v := range childValues {
+			bag.Set(k, v)
+		}
+	}
+	for from, to := range s.Outputs {
+		if v, ok := childValues[from]; ok {
+			bag.Set(to, v)
+		}
+	}
+	// Lo que el hijo consumio tampoco queda pendiente en el padre
+	if answers, ok := childValues[answersKey]; ok {
+		bag.Set(answersKey, answers)
+	} else {
+		bag.Delete(answersKey)
+	}
+
+	content := ""
+	var output interface{}
+	for i := len(response.Results) - 1; i >= 0; i-- {
+		if response.Results[i].Error == nil {
+			content = response.Results[i].Content
+			output = response.Results[i].Output
+			break
+		}
+	}
+	if s.IsolatedMemory && content != "" && interrupted == nil {
+		mem.Add("assistant", content)
+	}
+
+	return AgentResponse{
+		Content: content,
+		Output:  output,
+		Error:   err,
+	}
+}
@@ -0,0 +1,145 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSubGraphBagMapping(t *testing.T) {
+	child := NewGraph(NewBag[any](), NewSliceMemory(10))
+	child.AddNode("lookup", &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		bag.Set("result", "pedido de "+bag.Get("customer").(string))
+		mem.Add("assistant", "encontrado")
+		return AgentResponse{Content: "encontrado"}
+	}})
+	child.SetEntrypoint("lookup")
+
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.Bag.Set("name", "Ana")
+	g.Bag.Set("secret", "no pasa")
+	g.AddSubGraph("orders", child,
+		WithInputs(map[string]string{"name": "customer"}),
+		WithOutputs(map[string]string{"result": "order"}),
+		WithIsolatedMemory(),
+	)
+	g.SetEntrypoint("orders")
+	g.Mem.Add("user", "donde esta mi pedido?")
+
+	response, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Bag.Get("order") != "pedido de Ana" {
+		t.Fatalf("order = %v", response.Bag.Get("order"))
+	}
+	if response.Bag.Get("result") != nil || response.Bag.Get("customer") != nil {
+		t.Fatalf("unmapped keys leaked: %v", response.Bag.All())
+	}
+	if all := response.Mem.All(); len(all) != 2 || all[1].Content != "encontrado" {
+		t.Fatalf("memory = %+v", all)
+	}
+}
+
+func TestSubGraphSelfReference(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddNode("a", reply("uno"))
+	g.AddSubGraph("again", g)
+	g.AddRelation(Entrypoint, "a")
+	g.AddRelation("a", "again")
+
+	if !hasDiagnostic(g.Validate(), SeverityError, DiagInvalidSubGraph, "again") {
+		t.Fatalf("diagnostics = %v", g.Validate())
+	}
+	if _, err := g.Compile(); err == nil {
+		t.Fatal("expected Compile to fail")
+	}
+
+	_, err := g.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "contains the graph that runs it") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestSubGraphInterruptWithInputs(t *testing.T) {
+	research := reply("research")
+	child := NewGraph(NewBag[any](), NewSliceMemory(10))
+	child.AddNode("research", research)
+	child.AddNode("approve", NewHumanNode("approve", "Aprobar?"))
+	child.AddRelation(Entrypoint, "research")
+	child.AddRelation("research", "approve")
+
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	// Inputs no nombra la respuesta, igual tiene que llegar al hijo
+	g.AddSubGraph("review", child, WithInputs(map[string]string{}), WithOutputs(map[string]string{}))
+	g.AddNode("after", reply("listo"))
+	g.AddRelation(Entrypoint, "review")
+	g.AddRelation("review", "after")
+	g.SetCheckpointer(NewMemoryCheckpointer())
+	ctx := context.Background()
+
+	if diagnostics := g.Validate(); len(diagnostics) != 0 {
+		t.Fatalf("diagnostics = %v", diagnostics)
+	}
+
+	response, err := g.Run(ctx)
+	if !errors.Is(err, ErrInterrupted) || response.Interrupt.Agent != "review" {
+		t.Fatalf("err = %v, interrupt = %+v", err, response.Interrupt)
+	}
+
+	response, err = g.ResumeWithAnswer(ctx, response.RunID, "si")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.Results); got != "review,after" || response.Results[0].Content != "si" {
+		t.Fatalf("results = %+v", response.Results)
+	}
+	if _, pending := response.Bag.All()[answersKey]; pending {
+		t.Fatal("the answer was consumed by the child but is still pending in the parent")
+	}
+
+	// El hijo sigue desde approve: research no vuelve a correr
+	if research.visits != 1 {
+		t.Fatalf("research ran %d times, want 1", research.visits)
+	}
+	if got := strings.Join(response.Mem.ToArrayString(), ","); got != "research,si,listo" {
+		t.Fatalf("memory = %s", got)
+	}
+}
+
+func TestSubGraphResumeIsolatedMemory(t *testing.T) {
+	research := reply("research")
+	child := NewGraph(NewBag[any](), NewSliceMemory(10))
+	child.AddNode("research", research)
+	child.AddNode("approve", NewHumanNode("approve", "Aprobar?"))
+	child.AddNode("summary", &fakeNode{run: func(bag *Bag[any], mem Memory) AgentResponse {
+		return AgentResponse{Content: strings.Join(mem.ToArrayString(), ",")}
+	}})
+	child.AddRelation(Entrypoint, "research")
+	child.AddRelation("research", "approve")
+	child.AddRelation("approve", "summary")
+
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddSubGraph("review", child, WithIsolatedMemory())
+	g.SetEntrypoint("review")
+	g.SetCheckpointer(NewMemoryCheckpointer())
+	ctx := context.Background()
+
+	response, err := g.Run(ctx)
+	if !errors.Is(err, ErrInterrupted) || response.Interrupt.Child == nil {
+		t.Fatalf("err = %v, interrupt = %+v", err, response.Interrupt)
+	}
+
+	response, err = g.ResumeWithAnswer(ctx, response.RunID, "si")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// La memoria aislada del hijo vuelve con lo que tenia antes de la pausa
+	if research.visits != 1 || response.Results[0].Content != "research,si" {
+		t.Fatalf("research visits = %d, results = %+v", research.visits, response.Results)
+	}
+	if got := strings.Join(response.Mem.ToArrayString(), ","); got != "research,si" {
+		t.Fatalf("parent memory = %s", got)
+	}
+}
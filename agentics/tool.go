@@ -2,10 +2,11 @@ package agentics
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 )
 
-type ToolFunc func(ctx context.Context, bag *Bag[any], input *ToolParams) interface{}
+type ToolFunc func(ctx context.Context, bag *Bag[any], input *ToolParams) (interface{}, error)
 
 type ToolInterface interface {
 	GetName() string
@@ -15,11 +16,14 @@ type ToolInterface interface {
 }
 
 type ToolResponse struct {
-	Output string
+	Output  string
+	IsError bool
+	Error   error
 }
 
 type ToolParams struct {
-	Params map[string]interface{}
+	Params    map[string]interface{}
+	Arguments string
 }
 
 type Tool struct {
@@ -30,8 +34,65 @@ type Tool struct {
 }
 
 type DescriptionParams struct {
-	Name string
-	Type string
+	Name        string              `json:"name"`
+	Type        string              `json:"type"`
+	Description string              `json:"description,omitempty"`
+	Required    bool                `json:"required,omitempty"`
+	Enum        []interface{}       `json:"enum,omitempty"`
+	Default     interface{}         `json:"default,omitempty"`
+	Items       *DescriptionParams  `json:"items,omitempty"`
+	Properties  []DescriptionParams `json:"properties,omitempty"`
+}
+
+func (d DescriptionParams) Schema() map[string]interface{} {
+	schema := map[string]interface{}{}
+	if d.Type != "" {
+		schema["type"] = d.Type
+	}
+	if d.Description != "" {
+		schema["description"] = d.Description
+	}
+	if len(d.Enum) > 0 {
+		schema["enum"] = d.Enum
+	}
+	if d.Default != nil {
+		schema["default"] = d.Default
+	}
+	if d.Items != nil {
+		schema["items"] = d.Items.Schema()
+	}
+	if len(d.Properties) > 0 {
+		nested := ParametersSchema(d.Properties)
+		schema["type"] = "object"
+		schema["properties"] = nested["properties"]
+		if required, ok := nested["required"]; ok {
+			schema["required"] = required
+		}
+	}
+
+	return schema
+}
+
+func ParametersSchema(params []DescriptionParams) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for _, param := range params {
+		properties[param.Name] = param.Schema()
+		if param.Required {
+			required = append(required, param.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
 }
 
 var toolRegistry = make(map[string]ToolFunc)
@@ -45,7 +106,7 @@ func getTool(name string) (ToolFunc, bool) {
 	return fn, ok
 }
 
-func NewTool(name string, description string, parameters []DescriptionParams, function func(ctx context.Context, bag *Bag[any], input *ToolParams) interface{}) ToolInterface {
+func NewTool(name string, description string, parameters []DescriptionParams, function ToolFunc) ToolInterface {
 	return &Tool{
 		Name:        name,
 		Description: description,
@@ -66,18 +127,64 @@ func (t *Tool) GetParameters() []DescriptionParams {
 	return t.Parameters
 }
 
-func (t *Tool) Run(ctx context.Context, bag *Bag[any], input *ToolParams) *ToolResponse {
-	output := t.Function(ctx, bag, input)
-	var outputString string
+func (t *Tool) Run(ctx context.Context, bag *Bag[any], input *ToolParams) (response *ToolResponse) {
+	defer recoverTool(t.Name, &response)
+
+	// JSON decodifica todos los numeros como float64. Se convierte sobre una
+	// copia para no tocar los parametros de quien llama.
+	params := make(map[string]interface{}, len(input.Params))
+	for k, v := range input.Params {
+		if floatVal, ok := v.(float64); ok && floatVal == float64(int(floatVal)) {
+			v = int(floatVal)
+		}
+		params[k] = v
+	}
+
+	output, err := t.Function(ctx, bag, &ToolParams{
+		Params:    params,
+		Arguments: input.Arguments,
+	})
+	if err != nil {
+		return toolError(err)
+	}
+
+	return toolOutput(output)
+}
 
+func toolOutput(output interface{}) *ToolResponse {
 	switch output := output.(type) {
+	case nil:
+		return &ToolResponse{}
 	case string:
-		outputString = output
-	case int:
-		outputString = fmt.Sprintf("%d", output)
+		return &ToolResponse{Output: output}
+	case []byte:
+		return &ToolResponse{Output: string(output)}
+	case error:
+		return toolError(output)
+	case fmt.Stringer:
+		return &ToolResponse{Output: output.String()}
 	}
 
+	encoded, err := json.Marshal(output)
+	if err != nil {
+		return toolError(err)
+	}
+
+	return &ToolResponse{Output: string(encoded)}
+}
+
+func toolError(err error) *ToolResponse {
 	return &ToolResponse{
-		Output: outputString,
+		Output:  err.Error(),
+		IsError: true,
+		Error:   err,
+	}
+}
+
+// Un panic dentro de una tool no debe tirar abajo todo el grafo, se devuelve
+// como error para que el modelo pueda reintentar.
+func recoverTool(name string, response **ToolResponse) {
+	if r := recover(); r != nil {
+		*response = toolError(fmt.Errorf("tool %s panicked: %v", name, r))
 	}
 }
@@ -0,0 +1,84 @@
+package agentics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParametersSchema(t *testing.T) {
+	schema := ParametersSchema([]DescriptionParams{
+		{Name: "city", Type: "string", Description: "City name", Required: true},
+		{Name: "unit", Type: "string", Enum: []interface{}{"c", "f"}, Default: "c"},
+		{Name: "days", Type: "array", Items: &DescriptionParams{Type: "integer"}},
+		{Name: "location", Properties: []DescriptionParams{
+			{Name: "lat", Type: "number", Required: true},
+			{Name: "lon", Type: "number", Required: true},
+		}},
+	})
+
+	encoded, _ := json.Marshal(schema)
+	var got map[string]interface{}
+	json.Unmarshal(encoded, &got)
+
+	want := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"city"},
+		"properties": map[string]interface{}{
+			"city": map[string]interface{}{"type": "string", "description": "City name"},
+			"unit": map[string]interface{}{"type": "string", "enum": []interface{}{"c", "f"}, "default": "c"},
+			"days": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+			"location": map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"lat", "lon"},
+				"properties": map[string]interface{}{
+					"lat": map[string]interface{}{"type": "number"},
+					"lon": map[string]interface{}{"type": "number"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("schema = %s", encoded)
+	}
+}
+
+func TestToolErrorsGoBackToTheModel(t *testing.T) {
+	provider := &fakeProvider{responses: []*ModelResponse{
+		toolCalls(
+			ToolCall{Name: "fails", Arguments: `{}`, ToolCallID: "call_1"},
+			ToolCall{Name: "panics", Arguments: `{}`, ToolCallID: "call_2"},
+			ToolCall{Name: "missing", Arguments: `{}`, ToolCallID: "call_3"},
+			ToolCall{Name: "fails", Arguments: `{not json`, ToolCallID: "call_4"},
+		),
+		text("lo siento"),
+	}}
+	tools := []ToolInterface{
+		NewTool("fails", "", nil, func(ctx context.Context, bag *Bag[any], input *ToolParams) (interface{}, error) {
+			return nil, errors.New("service unavailable")
+		}),
+		NewTool("panics", "", nil, func(ctx context.Context, bag *Bag[any], input *ToolParams) (interface{}, error) {
+			panic("boom")
+		}),
+	}
+	agent := NewAgent("errors", "", withFake(provider), WithTools(tools))
+
+	response := agent.Run(context.Background(), NewBag[any](), NewSliceMemory(10))
+	if response.Error != nil || response.Content != "lo siento" {
+		t.Fatalf("response = %+v", response)
+	}
+
+	results := provider.call(1)[1:]
+	want := []string{"service unavailable", "tool panics panicked: boom", "tool missing not found", "invalid arguments for tool fails"}
+	if len(results) != len(want) {
+		t.Fatalf("tool results = %+v", results)
+	}
+	for i, result := range results {
+		if !result.IsError || !strings.Contains(result.Content, want[i]) {
+			t.Errorf("result %d = %+v, want error containing %q", i, result, want[i])
+		}
+	}
+}
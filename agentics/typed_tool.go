@@ -0,0 +1,271 @@
+package agentics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type TypedToolFunc[In, Out any] func(ctx context.Context, bag *Bag[any], input In) (Out, error)
+
+type TypedTool[In, Out any] struct {
+	Name        string
+	Description string
+	Parameters  []DescriptionParams
+	Function    TypedToolFunc[In, Out]
+}
+
+// NewTypedTool arma el schema de parametros a partir de los tags del struct In:
+// `json` para el nombre, `description`, `enum` (separado por comas) y `default`.
+// Los campos sin omitempty y que no son punteros se marcan como requeridos. Si
+// el modelo no manda un campo con default se usa ese valor. Los structs
+// embebidos se aplanan como en encoding/json.
+func NewTypedTool[In, Out any](name string, description string, function TypedToolFunc[In, Out]) ToolInterface {
+	t := reflect.TypeOf((*In)(nil)).Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic("typed tool input must be a struct: " + name)
+	}
+
+	return &TypedTool[In, Out]{
+		Name:        name,
+		Description: description,
+		Parameters:  structParams(t),
+		Function:    function,
+	}
+}
+
+func (t *TypedTool[In, Out]) GetName() string {
+	return t.Name
+}
+
+func (t *TypedTool[In, Out]) GetDescription() string {
+	return t.Description
+}
+
+func (t *TypedTool[In, Out]) GetParameters() []DescriptionParams {
+	return t.Parameters
+}
+
+func (t *TypedTool[In, Out]) Run(ctx context.Context, bag *Bag[any], input *ToolParams) (response *ToolResponse) {
+	defer recoverTool(t.Name, &response)
+
+	raw := []byte(input.Arguments)
+	if len(bytes.TrimSpace(raw)) == 0 {
+		var err error
+		if raw, err = json.Marshal(input.Params); err != nil {
+			return toolError(err)
+		}
+	}
+
+	var values map[string]interface{}
+	valuesDecoder := json.NewDecoder(bytes.NewReader(raw))
+	valuesDecoder.UseNumber()
+	if err := valuesDecoder.Decode(&values); err != nil {
+		return toolError(fmt.Errorf("invalid arguments: %w", err))
+	}
+	if applyDefaults(t.Parameters, values) {
+		var err error
+		if raw, err = json.Marshal(values); err != nil {
+			return toolError(err)
+		}
+	}
+	if err := validateParams(t.Parameters, values, ""); err != nil {
+		return toolError(fmt.Errorf("invalid arguments: %w", err))
+	}
+
+	var in In
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&in); err != nil {
+		return toolError(fmt.Errorf("invalid arguments: %w", err))
+	}
+
+	out, err := t.Function(ctx, bag, in)
+	if err != nil {
+		return toolError(err)
+	}
+
+	return toolOutput(out)
+}
+
+func structParams(t reflect.Type) []DescriptionParams {
+	params := []DescriptionParams{}
+	declared := map[string]bool{}
+	promoted := []DescriptionParams{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name := ""
+		omitempty := false
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			name = parts[0]
+			for _, opt := range parts[1:] {
+				if opt == "omitempty" {
+					omitempty = true
+				}
+			}
+		}
+
+		// Un struct embebido sin nombre en el tag suma sus campos a este nivel,
+		// aunque el tipo no sea exportado
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for _, param := range structParams(embedded) {
+					if field.Type.Kind() == reflect.Pointer {
+						param.Required = false
+					}
+					promoted = append(promoted, param)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		param := typeParam(field.Type)
+		param.Name = name
+		param.Description = field.Tag.Get("description")
+		param.Required = !omitempty && field.Type.Kind() != reflect.Pointer
+		if enum, ok := field.Tag.Lookup("enum"); ok {
+			for _, value := range strings.Split(enum, ",") {
+				param.Enum = append(param.Enum, tagValue(param.Type, strings.TrimSpace(value)))
+			}
+		}
+		if def, ok := field.Tag.Lookup("default"); ok {
+			param.Default = tagValue(param.Type, def)
+		}
+
+		declared[name] = true
+		params = append(params, param)
+	}
+
+	// Como en encoding/json, un campo de este nivel tapa al promovido
+	for _, param := range promoted {
+		if !declared[param.Name] {
+			declared[param.Name] = true
+			params = append(params, param)
+		}
+	}
+
+	return params
+}
+
+// applyDefaults completa los parametros que faltan con su default, tambien en
+// los objetos anidados. Devuelve true si cambio algo.
+func applyDefaults(params []DescriptionParams, values map[string]interface{}) bool {
+	changed := false
+	for _, param := range params {
+		value, ok := values[param.Name]
+		if (!ok || value == nil) && param.Default != nil {
+			values[param.Name] = param.Default
+			changed = true
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(param.Properties) > 0 {
+			if applyDefaults(param.Properties, nested) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// tagValue convierte el texto de un tag enum o default al tipo del campo, asi
+// el schema tiene 3 y no "3". Si no se puede convertir queda como string.
+func tagValue(kind string, value string) interface{} {
+	switch kind {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+func typeParam(t reflect.Type) DescriptionParams {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return DescriptionParams{Type: "string"}
+	case reflect.Bool:
+		return DescriptionParams{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return DescriptionParams{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return DescriptionParams{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		items := typeParam(t.Elem())
+		return DescriptionParams{Type: "array", Items: &items}
+	case reflect.Struct:
+		return DescriptionParams{Type: "object", Properties: structParams(t)}
+	case reflect.Map:
+		return DescriptionParams{Type: "object"}
+	}
+
+	return DescriptionParams{}
+}
+
+func validateParams(params []DescriptionParams, values map[string]interface{}, prefix string) error {
+	for _, param := range params {
+		value, ok := values[param.Name]
+		if !ok || value == nil {
+			if param.Required {
+				return fmt.Errorf("missing required parameter %q", prefix+param.Name)
+			}
+			continue
+		}
+
+		if len(param.Enum) > 0 {
+			allowed := false
+			for _, e := range param.Enum {
+				if fmt.Sprint(e) == fmt.Sprint(value) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return fmt.Errorf("parameter %q must be one of %v", prefix+param.Name, param.Enum)
+			}
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok && len(param.Properties) > 0 {
+			if err := validateParams(param.Properties, nested, prefix+param.Name+"."); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package agentics
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type forecastInput struct {
+	City  string   `json:"city" description:"City name"`
+	Days  int      `json:"days" enum:"1,3,7" default:"3"`
+	Ratio float64  `json:"ratio,omitempty" default:"0.5"`
+	Alert *bool    `json:"alert" default:"true"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+type forecastOutput struct {
+	City string `json:"city"`
+	Days int    `json:"days"`
+}
+
+func TestTypedToolSchema(t *testing.T) {
+	tool := NewTypedTool("forecast", "", func(ctx context.Context, bag *Bag[any], in forecastInput) (forecastOutput, error) {
+		return forecastOutput{}, nil
+	})
+
+	encoded, _ := json.Marshal(ParametersSchema(tool.GetParameters()))
+	want := `{"properties":{` +
+		`"alert":{"default":true,"type":"boolean"},` +
+		`"city":{"description":"City name","type":"string"},` +
+		`"days":{"default":3,"enum":[1,3,7],"type":"integer"},` +
+		`"ratio":{"default":0.5,"type":"number"},` +
+		`"tags":{"items":{"type":"string"},"type":"array"}},` +
+		`"required":["city","days"],"type":"object"}`
+	if string(encoded) != want {
+		t.Fatalf("schema = %s\nwant     %s", encoded, want)
+	}
+}
+
+func TestTypedToolRun(t *testing.T) {
+	tool := NewTypedTool("forecast", "", func(ctx context.Context, bag *Bag[any], in forecastInput) (forecastOutput, error) {
+		return forecastOutput{City: in.City, Days: in.Days}, nil
+	})
+
+	response := tool.Run(context.Background(), NewBag[any](), &ToolParams{Arguments: `{"city":"Lima","days":7}`})
+	if response.IsError || response.Output != `{"city":"Lima","days":7}` {
+		t.Fatalf("response = %+v", response)
+	}
+
+	for arguments, want := range map[string]string{
+		`{"city":"Lima","days":2}`:              "must be one of",
+		`{"days":3}`:                            `missing required parameter "city"`,
+		`{"city":"Lima","days":3,"extra":true}`: "unknown field",
+	} {
+		response := tool.Run(context.Background(), NewBag[any](), &ToolParams{Arguments: arguments})
+		if !response.IsError || !strings.Contains(response.Output, want) {
+			t.Errorf("%s: response = %+v, want error containing %q", arguments, response, want)
+		}
+	}
+}
+
+func TestTypedToolDefaults(t *testing.T) {
+	var got forecastInput
+	tool := NewTypedTool("forecast", "", func(ctx context.Context, bag *Bag[any], in forecastInput) (forecastOutput, error) {
+		got = in
+		return forecastOutput{}, nil
+	})
+
+	response := tool.Run(context.Background(), NewBag[any](), &ToolParams{Arguments: `{"city":"Lima"}`})
+	if response.IsError {
+		t.Fatal(response.Output)
+	}
+	if got.Days != 3 || got.Ratio != 0.5 || got.Alert == nil || !*got.Alert {
+		t.Fatalf("input = %+v, want the defaults", got)
+	}
+
+	// Lo que manda el modelo gana sobre el default
+	tool.Run(context.Background(), NewBag[any](), &ToolParams{Arguments: `{"city":"Lima","days":7,"alert":false}`})
+	if got.Days != 7 || got.Alert == nil || *got.Alert {
+		t.Fatalf("input = %+v", got)
+	}
+}
+
+type pagination struct {
+	Page  int `json:"page" default:"1"`
+	Limit int `json:"limit,omitempty"`
+}
+
+type Location struct {
+	Country string `json:"country,omitempty"`
+}
+
+type searchInput struct {
+	pagination
+	*Location
+	Query string `json:"query"`
+	Limit string `json:"limit,omitempty" description:"Outer field wins"`
+}
+
+func TestTypedToolEmbeddedStructs(t *testing.T) {
+	var got searchInput
+	tool := NewTypedTool("search", "", func(ctx context.Context, bag *Bag[any], in searchInput) (string, error) {
+		got = in
+		return "ok", nil
+	})
+
+	encoded, _ := json.Marshal(ParametersSchema(tool.GetParameters()))
+	want := `{"properties":{` +
+		`"country":{"type":"string"},` +
+		`"limit":{"description":"Outer field wins","type":"string"},` +
+		`"page":{"default":1,"type":"integer"},` +
+		`"query":{"type":"string"}},` +
+		`"required":["query","page"],"type":"object"}`
+	if string(encoded) != want {
+		t.Fatalf("schema = %s\nwant     %s", encoded, want)
+	}
+
+	// Los campos promovidos llegan planos, como los decodifica encoding/json
+	response := tool.Run(context.Background(), NewBag[any](), &ToolParams{Arguments: `{"query":"hotel","limit":"10","country":"PE"}`})
+	if response.IsError {
+		t.Fatal(response.Output)
+	}
+	if got.Query != "hotel" || got.Page != 1 || got.Limit != "10" || got.Location == nil || got.Country != "PE" {
+		t.Fatalf("input = %+v", got)
+	}
+}
+
+func TestToolRunDoesNotModifyParams(t *testing.T) {
+	var seen interface{}
+	tool := NewTool("count", "", nil, func(ctx context.Context, bag *Bag[any], input *ToolParams) (interface{}, error) {
+		seen = input.Params["n"]
+		return nil, nil
+	})
+
+	params := map[string]interface{}{"n": float64(3)}
+	tool.Run(context.Background(), NewBag[any](), &ToolParams{Params: params})
+
+	if _, ok := seen.(int); !ok {
+		t.Fatalf("tool got %T, want int", seen)
+	}
+	if _, ok := params["n"].(float64); !ok {
+		t.Fatalf("caller params changed to %T", params["n"])
+	}
+}
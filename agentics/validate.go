@@ -0,0 +1,404 @@
+package agentics
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type DiagnosticCode string
+
+const (
+	DiagMissingEntrypoint     DiagnosticCode = "missing_entrypoint"
+	DiagUnknownAgent          DiagnosticCode = "unknown_agent"
+	DiagUnknownBranch         DiagnosticCode = "unknown_branch"
+	DiagUnreachable           DiagnosticCode = "unreachable"
+	DiagMissingRoute          DiagnosticCode = "missing_route"
+	DiagUnregisteredHook      DiagnosticCode = "unregistered_hook"
+	DiagUnregisteredTool      DiagnosticCode = "unregistered_tool"
+	DiagUnregisteredGuardrail DiagnosticCode = "unregistered_guardrail"
+	DiagInvalidSubGraph       DiagnosticCode = "invalid_subgraph"
+	DiagMissingCheckpointer   DiagnosticCode = "missing_checkpointer"
+)
+
+type Diagnostic struct {
+	Severity Severity
+	Code     DiagnosticCode
+	Node     string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Node == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Severity, d.Node, d.Message)
+}
+
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		messages = append(messages, d.String())
+	}
+	return "invalid graph: " + strings.Join(messages, "; ")
+}
+
+func withDiagnostic(d Diagnostic) AgentOption {
+	return func(a *Agent) {
+		d.Node = a.Name
+		a.diagnostics = append(a.diagnostics, d)
+	}
+}
+
+// Validate revisa la definicion del grafo sin correrlo. Los diagnosticos con
+// SeverityError hacen fallar a Compile.
+func (g *Graph) Validate() []Diagnostic {
+	return g.validate(map[*Graph]bool{})
+}
+
+// parents tiene los grafos que se estan validando, para cortar un sub-grafo
+// que se contiene a si mismo.
+func (g *Graph) validate(parents map[*Graph]bool) []Diagnostic {
+	parents[g] = true
+	defer delete(parents, g)
+
+	diagnostics := []Diagnostic{}
+	add := func(severity Severity, code DiagnosticCode, node string, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: severity,
+			Code:     code,
+			Node:     node,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	exists := func(name string) bool {
+		if name == Entrypoint || name == Exitpoint {
+			return true
+		}
+		_, ok := g.Agents[name]
+		return ok
+	}
+	isJoin := func(name string) bool {
+		for _, join := range g.FanOuts {
+			if join.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	start, err := g.startNodes()
+	if err != nil {
+		add(SeverityError, DiagMissingEntrypoint, g.Entrypoint, "%v", err)
+	}
+
+	for _, relation := range g.Relations {
+		if !exists(relation[0]) && !isJoin(relation[0]) {
+			add(SeverityError, DiagUnknownAgent, relation[0], "edge %s -> %s starts at an unknown agent", relation[0], relation[1])
+		}
+		if !exists(relation[1]) {
+			add(SeverityError, DiagUnknownAgent, relation[0], "edge %s -> %s points to an unknown agent", relation[0], relation[1])
+		}
+	}
+
+	for _, name := range sortedKeys(g.ErrorPolicies) {
+		policy := g.ErrorPolicies[name]
+		if policy.Fallback != "" && !exists(policy.Fallback) {
+			add(SeverityError, DiagUnknownAgent, name, "fallback %q is not an agent", policy.Fallback)
+		}
+	}
+
+	for _, from := range sortedKeys(g.FanOuts) {
+		join := g.FanOuts[from]
+		if !exists(from) {
+			add(SeverityError, DiagUnknownAgent, from, "fan-out starts at an unknown agent")
+		}
+		for _, branch := range join.Branches {
+			if !exists(branch) {
+				add(SeverityError, DiagUnknownAgent, from, "fan-out branch %q is not an agent", branch)
+			}
+		}
+	}
+
+	for _, from := range sortedKeys(g.Conditions) {
+		edge := g.Conditions[from]
+		if !exists(from) && !isJoin(from) {
+			add(SeverityError, DiagUnknownAgent, from, "conditional edge starts at an unknown agent")
+		}
+		if edge.Route == nil {
+			add(SeverityError, DiagMissingRoute, from, "conditional edge has no route function")
+		}
+		if len(edge.Targets) == 0 {
+			add(SeverityWarning, DiagMissingRoute, from, "conditional edge declares no targets")
+		}
+		for _, target := range edge.Targets {
+			if !exists(target) {
+				add(SeverityError, DiagUnknownAgent, from, "conditional target %q is not an agent", target)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(g.MaxVisits) {
+		if !exists(name) {
+			add(SeverityWarning, DiagUnknownAgent, name, "max visits set for an unknown agent")
+		}
+	}
+
+	// Sin Checkpointer un interrupt no se puede reanudar. AskHuman dentro de
+	// una tool o un hook no se ve desde aca, solo los HumanNode.
+	if g.Checkpointer == nil {
+		for _, name := range sortedKeys(g.Agents) {
+			if asksHuman(g.Agents[name], map[*Graph]bool{}) {
+				add(SeverityError, DiagMissingCheckpointer, name, "waits for a human answer but the graph has no checkpointer")
+			}
+		}
+	}
+
+	conditional := false
+	for _, name := range sortedKeys(g.Agents) {
+		if sub, ok := g.Agents[name].(*SubGraph); ok {
+			diagnostics = append(diagnostics, sub.validate(name, parents)...)
+			continue
+		}
+		a, ok := g.Agents[name].(*Agent)
+		if !ok {
+			continue
+		}
+		diagnostics = append(diagnostics, a.diagnostics...)
+		for _, branch := range a.Branchs {
+			if !exists(branch) {
+				add(SeverityError, DiagUnknownBranch, name, "branch %q is not an agent", branch)
+			}
+		}
+		if a.Conditional != nil {
+			conditional = true
+		}
+	}
+
+	// Las rutas de un Conditional no se conocen hasta correrlo, asi que en ese
+	// caso los nodos inalcanzables quedan como warning.
+	reachable := g.reachable(start)
+	for _, name := range sortedKeys(g.Agents) {
+		if !reachable[name] {
+			severity := SeverityWarning
+			if !conditional {
+				severity = SeverityError
+			}
+			add(severity, DiagUnreachable, name, "agent is not reachable from the entrypoint")
+		}
+	}
+
+	return diagnostics
+}
+
+// Los diagnosticos del grafo hijo quedan con el nombre del nodo como prefijo.
+func (s *SubGraph) validate(name string, parents map[*Graph]bool) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, d := range s.diagnostics {
+		d.Node = name
+		diagnostics = append(diagnostics, d)
+	}
+	if s.Graph == nil {
+		return diagnostics
+	}
+	if parents[s.Graph] {
+		return append(diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Code:     DiagInvalidSubGraph,
+			Node:     name,
+			Message:  "sub-graph contains the graph that runs it",
+		})
+	}
+
+	for _, d := range s.Graph.validate(parents) {
+		// Los interrupts del hijo se reanudan con el Checkpointer del padre
+		if d.Code == DiagMissingCheckpointer {
+			continue
+		}
+		if d.Node == "" {
+			d.Node = name
+		} else {
+			d.Node = name + "/" + d.Node
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+func asksHuman(node AgentInterface, seen map[*Graph]bool) bool {
+	switch n := node.(type) {
+	case *HumanNode:
+		return true
+	case *SubGraph:
+		if n.Graph == nil || seen[n.Graph] {
+			return false
+		}
+		seen[n.Graph] = true
+		for _, child := range n.Graph.Agents {
+			if asksHuman(child, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (g *Graph) reachable(start []string) map[string]bool {
+	seen := make(map[string]bool)
+	queue := append([]string{}, start...)
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+
+		next := []string{}
+		for _, relation := range g.Relations {
+			if relation[0] == current {
+				next = append(next, relation[1])
+			}
+		}
+		if a, ok := g.Agents[current].(*Agent); ok {
+			next = append(next, a.Branchs...)
+		}
+		if policy, ok := g.ErrorPolicies[current]; ok && policy.Fallback != "" {
+			next = append(next, policy.Fallback)
+		}
+		if join, ok := g.FanOuts[current]; ok {
+			next = append(next, join.Branches...)
+			next = append(next, join.Name)
+		}
+		if edge, ok := g.Conditions[current]; ok {
+			next = append(next, edge.Targets...)
+		}
+
+		queue = append(queue, next...)
+	}
+
+	return seen
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CompiledGraph es una copia validada del grafo. Cambios posteriores sobre el
+// Graph original o sus agentes no la afectan; lo unico compartido son los
+// clientes de modelo, las tools, el Bag y la Memory.
+type CompiledGraph struct {
+	graph *Graph
+}
+
+func (g *Graph) Compile() (*CompiledGraph, error) {
+	diagnostics := g.Validate()
+
+	errs := []Diagnostic{}
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Diagnostics: errs}
+	}
+
+	return &CompiledGraph{graph: g.clone()}, nil
+}
+
+func (g *Graph) clone() *Graph {
+	agents := make(map[string]AgentInterface, len(g.Agents))
+	for name, agent := range g.Agents {
+		switch node := agent.(type) {
+		case *Agent:
+			agents[name] = node.clone()
+		case *SubGraph:
+			sub := *node
+			sub.Inputs = maps.Clone(node.Inputs)
+			sub.Outputs = maps.Clone(node.Outputs)
+			if node.Graph != nil {
+				sub.Graph = node.Graph.clone()
+			}
+			agents[name] = &sub
+		case *HumanNode:
+			human := *node
+			agents[name] = &human
+		default:
+			agents[name] = agent
+		}
+	}
+
+	relations := make([][]string, 0, len(g.Relations))
+	for _, relation := range g.Relations {
+		relations = append(relations, slices.Clone(relation))
+	}
+	fanOuts := make(map[string]Join, len(g.FanOuts))
+	for from, join := range g.FanOuts {
+		join.Branches = slices.Clone(join.Branches)
+		fanOuts[from] = join
+	}
+	conditions := make(map[string]ConditionalEdge, len(g.Conditions))
+	for from, edge := range g.Conditions {
+		edge.Targets = slices.Clone(edge.Targets)
+		conditions[from] = edge
+	}
+
+	return &Graph{
+		Entrypoint:    g.Entrypoint,
+		Agents:        agents,
+		Relations:     relations,
+		ErrorPolicies: maps.Clone(g.ErrorPolicies),
+		FanOuts:       fanOuts,
+		Conditions:    conditions,
+		Checkpointer:  g.Checkpointer,
+		Cyclic:        g.Cyclic,
+		MaxSteps:      g.MaxSteps,
+		MaxVisits:     maps.Clone(g.MaxVisits),
+		Bag:           g.Bag,
+		Mem:           g.Mem,
+	}
+}
+
+// clone copia el agente y sus slices. El Client no se copia: WithModel sobre
+// el agente original cambia el modelo de los dos.
+func (a *Agent) clone() *Agent {
+	agent := *a
+	agent.Branchs = slices.Clone(a.Branchs)
+	agent.Tools = slices.Clone(a.Tools)
+	agent.InputGuardrails = slices.Clone(a.InputGuardrails)
+	agent.OutputGuardrails = slices.Clone(a.OutputGuardrails)
+	agent.inputGuardrails = slices.Clone(a.inputGuardrails)
+	agent.outputGuardrails = slices.Clone(a.outputGuardrails)
+	agent.diagnostics = slices.Clone(a.diagnostics)
+	agent.hooks = slices.Clone(a.hooks)
+	return &agent
+}
+
+func (c *CompiledGraph) Run(ctx context.Context, opts ...RunOption) (*GraphResponse, error) {
+	return c.graph.Run(ctx, opts...)
+}
+
+func (c *CompiledGraph) RunStream(ctx context.Context, handler EventHandler, opts ...RunOption) (*GraphResponse, error) {
+	return c.graph.RunStream(ctx, handler, opts...)
+}
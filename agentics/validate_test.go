@@ -0,0 +1,84 @@
+package agentics
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func hasDiagnostic(diagnostics []Diagnostic, severity Severity, code DiagnosticCode, node string) bool {
+	for _, d := range diagnostics {
+		if d.Severity == severity && d.Code == code && d.Node == node {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGraphValidate(t *testing.T) {
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddAgent(NewAgent("router", "", WithBranchs([]string{"billing", "missing"}), WithHooks(PreHook, "test_not_registered")))
+	g.AddAgent(NewAgent("billing", ""))
+	g.AddAgent(NewAgent("orphan", ""))
+	g.AddRelation(Entrypoint, "router")
+	g.AddRelation("billing", "nowhere")
+
+	diagnostics := g.Validate()
+	for _, want := range []struct {
+		code DiagnosticCode
+		node string
+	}{
+		{DiagUnknownBranch, "router"},
+		{DiagUnknownAgent, "billing"},
+		{DiagUnreachable, "orphan"},
+		{DiagUnregisteredHook, "router"},
+	} {
+		if !hasDiagnostic(diagnostics, SeverityError, want.code, want.node) {
+			t.Errorf("missing %s on %s in %v", want.code, want.node, diagnostics)
+		}
+	}
+	if hasDiagnostic(diagnostics, SeverityError, DiagUnreachable, "billing") {
+		t.Error("billing is reachable through the router branches")
+	}
+	for _, d := range diagnostics {
+		if d.Code == DiagUnregisteredHook && d.Message != `hook "test_not_registered" is not registered` {
+			t.Errorf("message = %q", d.Message)
+		}
+	}
+
+	_, err := g.Compile()
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Diagnostics) == 0 {
+		t.Fatalf("err = %v, want a ValidationError", err)
+	}
+}
+
+func TestCompiledGraphIsACopy(t *testing.T) {
+	provider := &fakeProvider{responses: []*ModelResponse{text("hola")}}
+	agent := NewAgent("a", "Sos un asistente", withFake(provider))
+	g := NewGraph(NewBag[any](), NewSliceMemory(10))
+	g.AddAgent(agent)
+	g.AddRelation(Entrypoint, "a")
+
+	compiled, err := g.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nada de esto tiene que llegar al grafo compilado
+	agent.Instructions = "Cambiado despues de compilar"
+	agent.Branchs = append(agent.Branchs, "b")
+	g.AddAgent(NewAgent("b", ""))
+	g.AddRelation("a", "b")
+
+	response, err := compiled.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names(response.Results) != "a" {
+		t.Fatalf("results = %s, want only a", names(response.Results))
+	}
+	if provider.prompts[0] != "Sos un asistente" {
+		t.Fatalf("prompt = %q, want the one at compile time", provider.prompts[0])
+	}
+}
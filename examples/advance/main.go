@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/parisote/agentics/agentics"
 	"github.com/subosito/gotenv"
@@ -40,7 +41,10 @@ func main() {
 	graph.SetEntrypoint(agent1.Name)
 	graph.AddRelation("agent1", "agent2")
 	graph.AddRelation("agent2", "agent3")
-	response := graph.Run(context.Background())
+	response, err := graph.Run(context.Background())
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 	fmt.Printf("Steps: %d\n", response.Bag.Get("step"))
@@ -1,12 +1,24 @@
 package main
 
 import (
-	"context"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/parisote/agentics/agentics"
 )
 
+// Las sesiones que no se usan en este tiempo se descartan
+const sessionTTL = 30 * time.Minute
+
+// running serializa las corridas de una sesion: dos requests con el mismo
+// ?session= no pueden usar el mismo Bag y Memory a la vez
+type sessionEntry struct {
+	session  *agentics.Session
+	lastUsed time.Time
+	running  sync.Mutex
+}
+
 func main() {
 
 	bag := agentics.NewBag[any]()
@@ -16,11 +28,69 @@ func main() {
 	graph.AddAgent(agent)
 	graph.SetEntrypoint(agent.Name)
 
+	// Cada caller tiene su propia conversacion, identificada por ?session=
+	var mu sync.Mutex
+	sessions := map[string]*sessionEntry{}
+
+	go func() {
+		for range time.Tick(sessionTTL / 2) {
+			mu.Lock()
+			for id, entry := range sessions {
+				if time.Since(entry.lastUsed) > sessionTTL {
+					delete(sessions, id)
+				}
+			}
+			mu.Unlock()
+		}
+	}()
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		graph.Mem.Add("user", r.URL.Query().Get("input"))
-		response := graph.Run(ctx)
+		// Si el cliente corta, se cancelan las llamadas al modelo
+		ctx := r.Context()
+		id := r.URL.Query().Get("session")
+
+		// DELETE /?session=<id> termina la conversacion y borra su memoria
+		if r.Method == http.MethodDelete {
+			mu.Lock()
+			entry, ok := sessions[id]
+			delete(sessions, id)
+			mu.Unlock()
+			if ok {
+				entry.running.Lock()
+				entry.session.Mem.Clear()
+				entry.running.Unlock()
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		mu.Lock()
+		entry, ok := sessions[id]
+		if !ok {
+			session, err := graph.NewSession(id)
+			if err != nil {
+				mu.Unlock()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			entry = &sessionEntry{session: session}
+			sessions[session.ID] = entry
+		}
+		entry.lastUsed = time.Now()
+		session := entry.session
+		mu.Unlock()
+
+		entry.running.Lock()
+		defer entry.running.Unlock()
+
+		session.Mem.Add("user", r.URL.Query().Get("input"))
+		response, err := session.Run(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
+		w.Header().Set("X-Session-ID", session.ID)
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(response.Mem.LastN(1)[0].Content))
 	})
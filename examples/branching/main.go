@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/parisote/agentics/agentics"
 	"github.com/subosito/gotenv"
@@ -33,10 +34,16 @@ func main() {
 	graph.SetEntrypoint(orchestrator.Name)
 	graph.AddRelation("orchestrator", "english_agent")
 	graph.AddRelation("orchestrator", "spanish_agent")
-	response := graph.Run(context.Background())
+	response, err := graph.Run(context.Background())
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 
 	mem.Add("user", "Hola mundo")
-	response = graph.Run(context.Background())
+	response, err = graph.Run(context.Background())
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 }
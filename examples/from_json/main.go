@@ -50,10 +50,16 @@ func main() {
 	graph := agentics.FromJson(file)
 	graph.Mem.Add("user", "Hello world")
 
-	response := graph.Run(context.Background())
+	response, err := graph.Run(context.Background())
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 
 	graph.Mem.Add("user", "Hola mundo")
-	response = graph.Run(context.Background())
+	response, err = graph.Run(context.Background())
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 }
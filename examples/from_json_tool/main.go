@@ -21,12 +21,18 @@ func main() {
 	graph := agentics.FromJson(file)
 	graph.Mem.Add("user", "Cuanto es 30 / 3?")
 
-	response := graph.Run(context.Background())
+	response, err := graph.Run(context.Background())
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 	fmt.Println("result = ", response.Bag.Get("result"))
 
 	graph.Mem.Add("user", "Cuanto es 30 * 3?")
-	response = graph.Run(context.Background())
+	response, err = graph.Run(context.Background())
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 	fmt.Println("result = ", response.Bag.Get("result"))
 }
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"errors"
 
 	"github.com/parisote/agentics/agentics"
 )
@@ -11,17 +12,17 @@ func init() {
 	agentics.RegisterTool("multiply", multiplyTool)
 }
 
-func divideTool(ctx context.Context, bag *agentics.Bag[any], input *agentics.ToolParams) interface{} {
+func divideTool(ctx context.Context, bag *agentics.Bag[any], input *agentics.ToolParams) (interface{}, error) {
 	if input.Params["b"].(int) == 0 {
-		return "Error: Division by zero"
+		return nil, errors.New("division by zero")
 	}
 	result := input.Params["a"].(int) / input.Params["b"].(int)
 	bag.Set("result", result)
-	return result
+	return result, nil
 }
 
-func multiplyTool(ctx context.Context, bag *agentics.Bag[any], input *agentics.ToolParams) interface{} {
+func multiplyTool(ctx context.Context, bag *agentics.Bag[any], input *agentics.ToolParams) (interface{}, error) {
 	result := input.Params["a"].(int) * input.Params["b"].(int)
 	bag.Set("result", result)
-	return result
+	return result, nil
 }
@@ -21,7 +21,10 @@ func main() {
 	graph := agentics.FromJson(file)
 	graph.Mem.Add("user", "Cual es la temperatura en la ciudad de Buenos Aires?")
 
-	response := graph.Run(context.Background())
+	response, err := graph.Run(context.Background())
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 	fmt.Println("weather_c = ", response.Bag.Get("weather_c"))
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/parisote/agentics/agentics"
 	"github.com/subosito/gotenv"
@@ -23,7 +24,10 @@ func main() {
 	graph := agentics.NewGraph(bag, mem)
 	graph.AddAgent(agent)
 	graph.SetEntrypoint(agent.Name)
-	response := graph.Run(context.Background())
+	response, err := graph.Run(context.Background())
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 }
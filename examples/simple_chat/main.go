@@ -39,9 +39,17 @@ func main() {
 		fmt.Printf("[%s]: %s\n", username, userInput)
 
 		graph.Mem.Add("user", userInput)
-		response := graph.Run(context.Background())
 
-		fmt.Printf("[Sistema]: %s\n", response.Mem.LastN(1)[0].Content)
+		fmt.Print("[Sistema]: ")
+		_, err := graph.RunStream(context.Background(), func(e agentics.Event) {
+			if e.Type == agentics.EventToken {
+				fmt.Print(e.Delta)
+			}
+		})
+		fmt.Println()
+		if err != nil {
+			fmt.Printf("[Error]: %v\n", err)
+		}
 	}
 }
 
@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 
 	"github.com/parisote/agentics/agentics"
 	"github.com/subosito/gotenv"
 )
 
+type operands struct {
+	A int `json:"a" description:"First operand."`
+	B int `json:"b" description:"Second operand."`
+}
+
 func main() {
 	err := gotenv.Load()
 	if err != nil {
@@ -16,33 +23,19 @@ func main() {
 
 	ctx := context.Background()
 
-	toolMultiply := agentics.NewTool("multiply",
-		"Use this tool to multiply two numbers. "+
-			"The input is a map with two keys, 'a' and 'b', "+
-			"which are the integers to multiply.",
-		[]agentics.DescriptionParams{
-			{Name: "a", Type: "integer"},
-			{Name: "b", Type: "integer"},
-		},
-		func(ctx context.Context, bag *agentics.Bag[any], input *agentics.ToolParams) interface{} {
-			result := input.Params["a"].(int) * input.Params["b"].(int)
-			return result
+	toolMultiply := agentics.NewTypedTool("multiply",
+		"Use this tool to multiply two integers.",
+		func(ctx context.Context, bag *agentics.Bag[any], input operands) (int, error) {
+			return input.A * input.B, nil
 		})
 
-	toolDivide := agentics.NewTool("divide",
-		"Use this tool to divide two numbers. "+
-			"The input is a map with two keys, 'a' and 'b', "+
-			"which are the integers to divide.",
-		[]agentics.DescriptionParams{
-			{Name: "a", Type: "integer"},
-			{Name: "b", Type: "integer"},
-		},
-		func(ctx context.Context, bag *agentics.Bag[any], input *agentics.ToolParams) interface{} {
-			if input.Params["b"].(int) == 0 {
-				return "Error: Division by zero"
+	toolDivide := agentics.NewTypedTool("divide",
+		"Use this tool to divide two integers.",
+		func(ctx context.Context, bag *agentics.Bag[any], input operands) (int, error) {
+			if input.B == 0 {
+				return 0, errors.New("division by zero")
 			}
-			result := input.Params["a"].(int) / input.Params["b"].(int)
-			return result
+			return input.A / input.B, nil
 		})
 
 	agent := agentics.NewAgent("agent",
@@ -58,10 +51,16 @@ func main() {
 	graph.AddAgent(agent)
 	graph.SetEntrypoint(agent.Name)
 
-	response := graph.Run(ctx)
+	response, err := graph.Run(ctx)
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 
 	mem.Add("user", "how many is 30 / 10?")
-	response = graph.Run(ctx)
+	response, err = graph.Run(ctx)
+	if err != nil {
+		log.Fatalf("error running graph: %v", err)
+	}
 	fmt.Printf("Response: %s\n", response.Mem.LastN(1)[0].Content)
 }